@@ -2,7 +2,10 @@ module orcascan-orcascan-datasource
 
 go 1.24.6
 
-require github.com/grafana/grafana-plugin-sdk-go v0.281.0
+require (
+	github.com/grafana/grafana-plugin-sdk-go v0.281.0
+	github.com/prometheus/client_golang v1.23.2
+)
 
 require (
 	github.com/BurntSushi/toml v1.5.0 // indirect
@@ -34,6 +37,7 @@ require (
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/klauspost/cpuid/v2 v2.3.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
 	github.com/magefile/mage v1.15.0 // indirect
 	github.com/mattetti/filebuffer v1.0.1 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -45,7 +49,6 @@ require (
 	github.com/oklog/run v1.1.0 // indirect
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/pierrec/lz4/v4 v4.1.22 // indirect
-	github.com/prometheus/client_golang v1.23.2 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.67.1 // indirect
 	github.com/prometheus/procfs v0.16.1 // indirect
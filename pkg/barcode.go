@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gs1GroupSeparator is the FNC1 character that ends variable-length element strings
+// in raw GS1-128 and GS1 DataMatrix scans.
+const gs1GroupSeparator = "\x1d"
+
+// barcodeInfo is what parseBarcode learned about a scanned code. valid is only
+// meaningful when known is true, i.e. when the code is a GS1 symbology with a check digit.
+type barcodeInfo struct {
+	format string // EAN-8, EAN-13, UPC-A, UPC-E, GTIN-14 or GS1-128
+	known  bool
+	valid  bool
+
+	// GS1-128 application identifiers.
+	gtin   string
+	batch  string
+	expiry time.Time
+	serial string
+	hasAIs bool
+}
+
+// barcodeColumnInfo records which extra columns a barcode column produced.
+type barcodeColumnInfo struct {
+	known  bool
+	hasAIs bool
+}
+
+// parseBarcode classifies a scanned value. Digit-only codes are checked against the GS1
+// check digit; GS1-128 element strings are split into their application identifiers.
+func parseBarcode(value string, loc *time.Location) barcodeInfo {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return barcodeInfo{}
+	}
+	if isDigits(s) {
+		return parseGTIN(s)
+	}
+	if info, ok := parseGS1ElementString(s, loc); ok {
+		return info
+	}
+	return barcodeInfo{}
+}
+
+func parseGTIN(s string) barcodeInfo {
+	switch len(s) {
+	case 8:
+		if gs1CheckDigitValid(s) {
+			return barcodeInfo{format: "EAN-8", known: true, valid: true}
+		}
+		if expanded, ok := expandUPCE(s); ok && gs1CheckDigitValid(expanded) {
+			return barcodeInfo{format: "UPC-E", known: true, valid: true}
+		}
+		return barcodeInfo{format: "EAN-8", known: true}
+	case 12:
+		return barcodeInfo{format: "UPC-A", known: true, valid: gs1CheckDigitValid(s)}
+	case 13:
+		return barcodeInfo{format: "EAN-13", known: true, valid: gs1CheckDigitValid(s)}
+	case 14:
+		return barcodeInfo{format: "GTIN-14", known: true, valid: gs1CheckDigitValid(s)}
+	}
+	return barcodeInfo{}
+}
+
+// gs1CheckDigitValid applies the GS1 mod-10 check: from the right, digits before the
+// check digit are weighted 3, 1, 3...
+func gs1CheckDigitValid(code string) bool {
+	if len(code) < 2 || !isDigits(code) {
+		return false
+	}
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
+
+// expandUPCE expands an 8-digit UPC-E code (number system 0 or 1) to its UPC-A form.
+func expandUPCE(code string) (string, bool) {
+	if len(code) != 8 || !isDigits(code) || (code[0] != '0' && code[0] != '1') {
+		return "", false
+	}
+	ns, d, check := code[:1], code[1:7], code[7:]
+	var body string
+	switch d[5] {
+	case '0', '1', '2':
+		body = d[0:2] + d[5:6] + "0000" + d[2:5]
+	case '3':
+		body = d[0:3] + "00000" + d[3:5]
+	case '4':
+		body = d[0:4] + "00000" + d[4:5]
+	default:
+		body = d[0:5] + "0000" + d[5:6]
+	}
+	return ns + body + check, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseGS1ElementString reads GS1-128 data written with parenthesised AIs, e.g.
+// "(01)09501101530003(17)250101(10)AB12", or raw with an optional symbology identifier
+// and FNC1 separators. A raw string without either is only accepted when it starts with
+// a valid (01) GTIN.
+func parseGS1ElementString(s string, loc *time.Location) (barcodeInfo, bool) {
+	var elements map[string]string
+	var ok bool
+	if strings.HasPrefix(s, "(") {
+		elements, ok = splitParenthesisedAIs(s)
+	} else {
+		raw := s
+		explicit := strings.Contains(raw, gs1GroupSeparator)
+		for _, id := range []string{"]C1", "]d2", "]Q3", "]e0"} {
+			if strings.HasPrefix(raw, id) {
+				raw = raw[len(id):]
+				explicit = true
+				break
+			}
+		}
+		if !explicit && !(len(raw) > 16 && strings.HasPrefix(raw, "01") && gs1CheckDigitValid(raw[2:16])) {
+			return barcodeInfo{}, false
+		}
+		elements, ok = splitRawAIs(raw)
+	}
+	if !ok || len(elements) == 0 {
+		return barcodeInfo{}, false
+	}
+
+	info := barcodeInfo{format: "GS1-128", known: true, valid: true, hasAIs: true}
+	if gtin, ok := elements["01"]; ok {
+		info.gtin = gtin
+		info.valid = len(gtin) == 14 && gs1CheckDigitValid(gtin)
+	}
+	info.batch = elements["10"]
+	info.serial = elements["21"]
+	if raw, ok := elements["17"]; ok {
+		expiry, err := parseGS1Date(raw, loc)
+		if err != nil {
+			info.valid = false
+		} else {
+			info.expiry = expiry
+		}
+	}
+	return info, true
+}
+
+func splitParenthesisedAIs(s string) (map[string]string, bool) {
+	elements := make(map[string]string)
+	for s != "" {
+		if s[0] != '(' {
+			return nil, false
+		}
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, false
+		}
+		ai := s[1:end]
+		if len(ai) < 2 || len(ai) > 4 || !isDigits(ai) {
+			return nil, false
+		}
+		s = s[end+1:]
+		next := strings.IndexByte(s, '(')
+		if next < 0 {
+			next = len(s)
+		}
+		elements[ai] = strings.TrimSpace(s[:next])
+		s = s[next:]
+	}
+	return elements, true
+}
+
+func splitRawAIs(s string) (map[string]string, bool) {
+	elements := make(map[string]string)
+	for s != "" {
+		s = strings.TrimPrefix(s, gs1GroupSeparator)
+		if s == "" {
+			break
+		}
+		aiLen := gs1AILength(s)
+		if aiLen == 0 || len(s) < aiLen || !isDigits(s[:aiLen]) {
+			return nil, false
+		}
+		ai := s[:aiLen]
+		s = s[aiLen:]
+
+		if n := gs1FixedLength(ai); n > 0 {
+			if len(s) < n {
+				return nil, false
+			}
+			elements[ai] = s[:n]
+			s = s[n:]
+			continue
+		}
+		end := strings.Index(s, gs1GroupSeparator)
+		if end < 0 {
+			end = len(s)
+		}
+		elements[ai] = s[:end]
+		s = s[end:]
+	}
+	return elements, true
+}
+
+// gs1AILength returns the length of the application identifier at the start of s.
+func gs1AILength(s string) int {
+	if len(s) < 2 || !isDigits(s[:2]) {
+		return 0
+	}
+	prefix, _ := strconv.Atoi(s[:2])
+	switch {
+	case prefix <= 22, prefix == 30, prefix == 37, prefix >= 90:
+		return 2
+	case prefix <= 29, prefix >= 40 && prefix <= 49:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// gs1FixedLength returns the data length of fixed-length AIs, or 0 when the element
+// runs to the next FNC1.
+func gs1FixedLength(ai string) int {
+	switch {
+	case ai == "00":
+		return 18
+	case ai == "01", ai == "02", ai == "03":
+		return 14
+	case ai == "04":
+		return 16
+	case len(ai) == 2 && ai[0] == '1' && ai != "10":
+		return 6
+	case ai == "20":
+		return 2
+	case len(ai) == 4 && ai[0] == '3' && ai[1] >= '1' && ai[1] <= '6':
+		return 6
+	case len(ai) == 3 && ai[:2] == "41":
+		return 13
+	}
+	return 0
+}
+
+// parseGS1Date reads a YYMMDD date; a day of 00 means the last day of the month.
+func parseGS1Date(raw string, loc *time.Location) (time.Time, error) {
+	if len(raw) != 6 || !isDigits(raw) {
+		return time.Time{}, fmt.Errorf("invalid GS1 date %q", raw)
+	}
+	year, _ := strconv.Atoi(raw[0:2])
+	month, _ := strconv.Atoi(raw[2:4])
+	day, _ := strconv.Atoi(raw[4:6])
+	if month < 1 || month > 12 || day > 31 {
+		return time.Time{}, fmt.Errorf("invalid GS1 date %q", raw)
+	}
+	if day == 0 {
+		return time.Date(2000+year, time.Month(month)+1, 0, 0, 0, 0, 0, loc), nil
+	}
+	ts := time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if ts.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid GS1 date %q", raw)
+	}
+	return ts, nil
+}
+
+// isBarcodeValue reports whether val is a GS1 code with a valid check digit. Bare
+// 8-digit codes are too easily confused with other numbers to count on their own, so
+// long reports whether the value was 12+ digits or a GS1-128 string.
+func isBarcodeValue(val any) (ok, long bool) {
+	s, isString := val.(string)
+	if !isString {
+		return false, false
+	}
+	info := parseBarcode(s, time.UTC)
+	if !info.known || !info.valid {
+		return false, false
+	}
+	return true, info.format != "EAN-8" && info.format != "UPC-E"
+}
+
+// normalizeBarcode keeps barcodes as trimmed strings.
+func normalizeBarcode(value any) any {
+	s, ok := stringifyValue(value).(string)
+	if !ok {
+		return value
+	}
+	return strings.TrimSpace(s)
+}
+
+// extendRowsWithBarcodes adds <key>_format and <key>_valid to each row for barcode
+// columns holding at least one known symbology, plus <key>_gtin, _batch, _expiry and
+// _serial when GS1-128 data is present. _valid is false for GS1 codes with a bad check
+// digit and null for other symbologies.
+func extendRowsWithBarcodes(rows []map[string]any, descriptors map[string]fieldDescriptor) ([]map[string]any, map[string]barcodeColumnInfo) {
+	columns := make(map[string]barcodeColumnInfo)
+	for key, desc := range descriptors {
+		if desc.kind == fieldKindBarcode {
+			columns[key] = barcodeColumnInfo{}
+		}
+	}
+	if len(columns) == 0 || len(rows) == 0 {
+		return rows, map[string]barcodeColumnInfo{}
+	}
+
+	parsed := make([]map[string]barcodeInfo, len(rows))
+	for i, row := range rows {
+		parsed[i] = make(map[string]barcodeInfo, len(columns))
+		for key := range columns {
+			s, ok := normalizeBarcode(row[key]).(string)
+			if !ok {
+				continue
+			}
+			info := parseBarcode(s, descriptors[key].opts.loc())
+			parsed[i][key] = info
+			column := columns[key]
+			column.known = column.known || info.known
+			column.hasAIs = column.hasAIs || info.hasAIs
+			columns[key] = column
+		}
+	}
+
+	extended := make([]map[string]any, 0, len(rows))
+	for i, row := range rows {
+		out := make(map[string]any, len(row)+2*len(columns))
+		for key, val := range row {
+			out[key] = val
+		}
+		for key, column := range columns {
+			info, ok := parsed[i][key]
+			if !ok || !column.known {
+				continue
+			}
+			out[key+"_format"] = nilIfEmpty(info.format)
+			if info.known {
+				out[key+"_valid"] = info.valid
+			}
+			if column.hasAIs {
+				out[key+"_gtin"] = nilIfEmpty(info.gtin)
+				out[key+"_batch"] = nilIfEmpty(info.batch)
+				out[key+"_serial"] = nilIfEmpty(info.serial)
+				if !info.expiry.IsZero() {
+					out[key+"_expiry"] = info.expiry
+				}
+			}
+		}
+		extended = append(extended, out)
+	}
+	return extended, columns
+}
+
+func nilIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// extendFieldDescriptorsForBarcodes adds descriptors for the columns that
+// extendRowsWithBarcodes produced, directly after their barcode column.
+func extendFieldDescriptorsForBarcodes(list []fieldDescriptor, mapping map[string]fieldDescriptor, columns map[string]barcodeColumnInfo) ([]fieldDescriptor, map[string]fieldDescriptor) {
+	if len(columns) == 0 {
+		return list, mapping
+	}
+
+	extended := make([]fieldDescriptor, 0, len(list)+2*len(columns))
+	newMapping := make(map[string]fieldDescriptor, len(mapping)+2*len(columns))
+	add := func(desc fieldDescriptor) {
+		extended = append(extended, desc)
+		newMapping[desc.meta.Key] = desc
+	}
+
+	for _, desc := range list {
+		add(desc)
+
+		column, ok := columns[desc.meta.Key]
+		if !ok || !column.known || desc.kind != fieldKindBarcode {
+			continue
+		}
+		key, label := desc.meta.Key, labelOrKey(desc.meta)
+		add(fieldDescriptor{meta: orcaField{Key: key + "_format", Label: label + " Format"}, kind: fieldKindString})
+		add(fieldDescriptor{meta: orcaField{Key: key + "_valid", Label: label + " Valid", Type: "boolean"}, kind: fieldKindBoolean})
+		if column.hasAIs {
+			add(fieldDescriptor{meta: orcaField{Key: key + "_gtin", Label: label + " GTIN"}, kind: fieldKindBarcode})
+			add(fieldDescriptor{meta: orcaField{Key: key + "_batch", Label: label + " Batch"}, kind: fieldKindString})
+			add(fieldDescriptor{meta: orcaField{Key: key + "_expiry", Label: label + " Expiry", Type: "date"}, kind: fieldKindTime, opts: desc.opts})
+			add(fieldDescriptor{meta: orcaField{Key: key + "_serial", Label: label + " Serial"}, kind: fieldKindString})
+		}
+	}
+	return extended, newMapping
+}
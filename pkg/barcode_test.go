@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBarcodeCheckDigits(t *testing.T) {
+	cases := []struct {
+		code   string
+		format string
+		valid  bool
+	}{
+		{"4006381333931", "EAN-13", true},
+		{"4006381333932", "EAN-13", false},
+		{"036000291452", "UPC-A", true},
+		{"96385074", "EAN-8", true},
+		{"04252614", "UPC-E", true},
+		{"10012345678902", "GTIN-14", true},
+		{"10012345678900", "GTIN-14", false},
+	}
+	for _, tc := range cases {
+		info := parseBarcode(tc.code, time.UTC)
+		if !info.known || info.format != tc.format || info.valid != tc.valid {
+			t.Errorf("parseBarcode(%q) = %+v; want %s valid=%v", tc.code, info, tc.format, tc.valid)
+		}
+	}
+
+	if info := parseBarcode("https://orcascan.com", time.UTC); info.known {
+		t.Fatalf("expected URL not to be a GS1 code, got %+v", info)
+	}
+}
+
+func TestParseBarcodeGS1128(t *testing.T) {
+	want := barcodeInfo{
+		format: "GS1-128",
+		known:  true,
+		valid:  true,
+		gtin:   "09501101530003",
+		batch:  "AB12",
+		serial: "S1",
+		expiry: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC),
+		hasAIs: true,
+	}
+	for _, code := range []string{
+		"(01)09501101530003(17)250100(10)AB12(21)S1",
+		"]C101095011015300031725010010AB12\x1d21S1",
+		"01095011015300031725010010AB12\x1d21S1",
+	} {
+		if got := parseBarcode(code, time.UTC); got != want {
+			t.Errorf("parseBarcode(%q) = %+v; want %+v", code, got, want)
+		}
+	}
+
+	if got := parseBarcode("(01)09501101530004(10)X", time.UTC); got.valid {
+		t.Fatalf("expected bad GTIN check digit to be invalid, got %+v", got)
+	}
+}
+
+func TestBarcodeColumns(t *testing.T) {
+	rows := []map[string]any{
+		{"Code": "4006381333931"},
+		{"Code": "036000291452"},
+		{"Code": "(01)09501101530003(10)LOT7"},
+	}
+	if kind := detectKindFromRows("Code", rows, fieldKindString); kind != fieldKindBarcode {
+		t.Fatalf("expected barcode kind from values, got %v", kind)
+	}
+
+	rows = append(rows, map[string]any{"Code": "4006381333932"}, map[string]any{"Code": "ABC-1"})
+	descList, descMap := buildFieldDescriptors([]orcaField{{Key: "Code", Format: "barcode"}}, rows, parseOptions{})
+	extendedRows, columns := extendRowsWithBarcodes(rows, descMap)
+	descList, descMap = extendFieldDescriptorsForBarcodes(descList, descMap, columns)
+	normalized := normalizeRows(extendedRows, descMap)
+
+	keys := make([]string, 0, len(descList))
+	for _, desc := range descList {
+		keys = append(keys, desc.meta.Key)
+	}
+	wantKeys := []string{"Code", "Code_format", "Code_valid", "Code_gtin", "Code_batch", "Code_expiry", "Code_serial"}
+	if len(keys) != len(wantKeys) {
+		t.Fatalf("expected columns %v, got %v", wantKeys, keys)
+	}
+	for i := range keys {
+		if keys[i] != wantKeys[i] {
+			t.Fatalf("expected columns %v, got %v", wantKeys, keys)
+		}
+	}
+
+	if normalized[0]["Code_valid"] != true || normalized[3]["Code_valid"] != false {
+		t.Fatalf("expected check digit flags, got %v and %v", normalized[0]["Code_valid"], normalized[3]["Code_valid"])
+	}
+	if _, ok := normalized[4]["Code_valid"]; ok {
+		t.Fatalf("expected no validity for non-GS1 value, got %v", normalized[4]["Code_valid"])
+	}
+	if normalized[2]["Code_gtin"] != "09501101530003" || normalized[2]["Code_batch"] != "LOT7" {
+		t.Fatalf("expected GS1-128 elements, got %v", normalized[2])
+	}
+}
+
+func TestBarcodeColumnsSkipUnknownSymbologies(t *testing.T) {
+	rows := []map[string]any{{"SKU": "ABC-1"}, {"SKU": "XYZ-22"}}
+	descList, descMap := buildFieldDescriptors([]orcaField{{Key: "SKU", Format: "barcode"}}, rows, parseOptions{})
+	extendedRows, columns := extendRowsWithBarcodes(rows, descMap)
+	descList, _ = extendFieldDescriptorsForBarcodes(descList, descMap, columns)
+
+	if len(descList) != 1 || descList[0].meta.Key != "SKU" {
+		t.Fatalf("expected no extra columns for unknown symbologies, got %+v", descList)
+	}
+	for _, row := range extendedRows {
+		if _, ok := row["SKU_format"]; ok {
+			t.Fatalf("expected no format cell, got %v", row)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalJSON decodes the rows while recording the order their keys first appear in,
+// which Go maps lose. Sheets without field metadata use it as their column order.
+func (r *orcaRowsResponse) UnmarshalJSON(body []byte) error {
+	var raw struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return err
+	}
+
+	r.Data = make([]map[string]any, 0, len(raw.Data))
+	r.Columns = nil
+	seen := make(map[string]struct{})
+	for _, item := range raw.Data {
+		row, keys, err := decodeOrderedRow(item)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				r.Columns = append(r.Columns, key)
+			}
+		}
+		r.Data = append(r.Data, row)
+	}
+	return nil
+}
+
+// decodeOrderedRow decodes a JSON object like json.Unmarshal into a map, also returning
+// its keys in document order. A null row decodes to a nil map.
+func decodeOrderedRow(raw json.RawMessage) (map[string]any, []string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if tok == nil {
+		return nil, nil, nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("row is %v, not an object", tok)
+	}
+
+	row := make(map[string]any)
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+
+		var val any
+		if err := dec.Decode(&val); err != nil {
+			return nil, nil, err
+		}
+		if _, dup := row[key]; !dup {
+			keys = append(keys, key)
+		}
+		row[key] = val
+	}
+	return row, keys, nil
+}
+
+// fieldsFromColumns builds field metadata from an ordered column list, for sheets whose
+// metadata is unavailable.
+func fieldsFromColumns(columns []string) []orcaField {
+	fields := make([]orcaField, 0, len(columns))
+	for _, key := range columns {
+		fields = append(fields, orcaField{Key: key, Label: key})
+	}
+	return fields
+}
+
+// appendDescriptorColumns returns columns followed by the descriptor keys it lacks, so
+// columns derived after the fetch keep a stable place after the sheet's own.
+func appendDescriptorColumns(columns []string, descList []fieldDescriptor) []string {
+	out := append([]string(nil), columns...)
+	seen := make(map[string]struct{}, len(columns)+len(descList))
+	for _, key := range columns {
+		seen[key] = struct{}{}
+	}
+	for _, desc := range descList {
+		if _, ok := seen[desc.meta.Key]; !ok {
+			seen[desc.meta.Key] = struct{}{}
+			out = append(out, desc.meta.Key)
+		}
+	}
+	return out
+}
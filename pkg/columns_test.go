@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrcaRowsResponseColumnOrder(t *testing.T) {
+	body := `{"data": [
+		{"_id": "a", "Zone": "B", "Barcode": "123", "Qty": 1},
+		null,
+		{"_id": "b", "Qty": 2, "Notes": {"x": 1}, "Zone": "C"}
+	]}`
+
+	var resp orcaRowsResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	want := []string{"_id", "Zone", "Barcode", "Qty", "Notes"}
+	if !reflect.DeepEqual(resp.Columns, want) {
+		t.Fatalf("got columns %v, want %v", resp.Columns, want)
+	}
+	if len(resp.Data) != 3 || resp.Data[1] != nil {
+		t.Fatalf("unexpected rows %v", resp.Data)
+	}
+	if resp.Data[0]["Qty"] != 1.0 || !reflect.DeepEqual(resp.Data[2]["Notes"], map[string]any{"x": 1.0}) {
+		t.Fatalf("unexpected values %v", resp.Data)
+	}
+}
+
+func TestOrcaRowsResponseRejectsNonObjectRows(t *testing.T) {
+	var resp orcaRowsResponse
+	if err := json.Unmarshal([]byte(`{"data": [[1, 2]]}`), &resp); err == nil {
+		t.Fatal("expected an error for an array row")
+	}
+}
+
+func TestFallbackFieldInfosStableOrder(t *testing.T) {
+	rows := []map[string]any{{"b": "x", "a": 1.0, "c": true, "when": "2024-01-02T03:04:05Z"}}
+	columns := []string{"b", "a", "c", "when"}
+	first := fallbackFieldInfos(rows, columns, "when", parseOptions{})
+	for i := 0; i < 20; i++ {
+		if next := fallbackFieldInfos(rows, columns, "when", parseOptions{}); !reflect.DeepEqual(first, next) {
+			t.Fatalf("field order changed between calls: %v vs %v", first, next)
+		}
+	}
+
+	keys := make([]string, 0, len(first))
+	for _, f := range first {
+		keys = append(keys, f.Key)
+	}
+	if want := []string{"when", "b", "a", "c"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("got keys %v, want %v", keys, want)
+	}
+}
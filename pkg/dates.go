@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// dateOrder selects how numeric dates such as 03/04/2025 are read.
+type dateOrder int
+
+const (
+	dateOrderAuto dateOrder = iota
+	dateOrderDMY
+	dateOrderMDY
+	dateOrderYMD
+)
+
+func (o dateOrder) String() string {
+	switch o {
+	case dateOrderDMY:
+		return "DMY"
+	case dateOrderMDY:
+		return "MDY"
+	case dateOrderYMD:
+		return "YMD"
+	default:
+		return "auto"
+	}
+}
+
+func parseDateOrder(v string) (dateOrder, error) {
+	switch strings.ToUpper(strings.TrimSpace(v)) {
+	case "", "AUTO":
+		return dateOrderAuto, nil
+	case "DMY":
+		return dateOrderDMY, nil
+	case "MDY":
+		return dateOrderMDY, nil
+	case "YMD":
+		return dateOrderYMD, nil
+	default:
+		return dateOrderAuto, newRequestError("unknown date order %q (expected DMY, MDY, YMD or auto)", v)
+	}
+}
+
+type dateOrderDetection struct {
+	order        dateOrder // dateOrderAuto when no value needed an order or none fits every value
+	ambiguous    bool      // every value reads both as DMY and as MDY
+	inconsistent bool      // no single order reads every value
+}
+
+// detectDateOrder inspects the string values of a column that are not ISO dates and
+// picks the only day/month order consistent with all of them. Values that read as a date
+// in neither order are not dates at all and are left to the detection threshold. When
+// both orders fit, the column is ambiguous and DMY is kept for compatibility.
+func detectDateOrder(values []any) dateOrderDetection {
+	dmy := parseOptions{dateOrder: dateOrderDMY}
+	mdy := parseOptions{dateOrder: dateOrderMDY}
+	iso := parseOptions{dateOrder: dateOrderYMD}
+
+	dmyOK, mdyOK, seen := true, true, false
+	for _, val := range values {
+		v, ok := val.(string)
+		if !ok || strings.TrimSpace(v) == "" {
+			continue
+		}
+		if _, err := parseOrcaTimeString(v, iso); err == nil {
+			continue
+		}
+
+		_, dmyErr := parseOrcaTimeString(v, dmy)
+		_, mdyErr := parseOrcaTimeString(v, mdy)
+		if dmyErr != nil && mdyErr != nil {
+			continue
+		}
+		seen = true
+		dmyOK = dmyOK && dmyErr == nil
+		mdyOK = mdyOK && mdyErr == nil
+		if !dmyOK && !mdyOK {
+			break
+		}
+	}
+
+	switch {
+	case !seen:
+		return dateOrderDetection{}
+	case dmyOK && mdyOK:
+		return dateOrderDetection{order: dateOrderDMY, ambiguous: true}
+	case dmyOK:
+		return dateOrderDetection{order: dateOrderDMY}
+	case mdyOK:
+		return dateOrderDetection{order: dateOrderMDY}
+	default:
+		return dateOrderDetection{inconsistent: true}
+	}
+}
+
+// numericTime selects how bare numbers are read as times.
+type numericTime int
+
+const (
+	numericTimeAuto numericTime = iota
+	numericTimeEpochSeconds
+	numericTimeEpochMillis
+	numericTimeExcel
+)
+
+// excelEpoch is day zero of the Excel 1900 date system, adjusted for its phantom 29 Feb 1900.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// timeFromNumber reads f as epoch seconds, epoch milliseconds or an Excel serial day
+// number. In auto mode the unit is guessed from the magnitude, covering roughly 1954-2119
+// for serials and 1973 onwards for epochs.
+func timeFromNumber(f float64, opts parseOptions) (time.Time, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, fmt.Errorf("invalid time number")
+	}
+
+	mode := opts.numericTime
+	if mode == numericTimeAuto {
+		abs := math.Abs(f)
+		switch {
+		case abs >= 1e11 && abs < 1e14:
+			mode = numericTimeEpochMillis
+		case abs >= 1e8 && abs < 1e11:
+			mode = numericTimeEpochSeconds
+		case f >= 20000 && f < 80000:
+			mode = numericTimeExcel
+		default:
+			return time.Time{}, fmt.Errorf("number %v is not a recognised timestamp", f)
+		}
+	}
+
+	switch mode {
+	case numericTimeEpochSeconds:
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+	case numericTimeEpochMillis:
+		return time.UnixMilli(int64(math.Round(f))).UTC(), nil
+	default:
+		days, frac := math.Modf(f)
+		base := excelEpoch.AddDate(0, 0, int(days))
+		wall := base.Add(time.Duration(math.Round(frac*86400)) * time.Second)
+		return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, opts.loc()), nil
+	}
+}
+
+// normalizeMeridiem upper-cases a trailing am/pm marker so Go's PM layouts accept it.
+func normalizeMeridiem(v string) string {
+	lower := strings.ToLower(v)
+	for _, suffix := range []string{" am", " pm", " a.m.", " p.m."} {
+		if strings.HasSuffix(lower, suffix) {
+			marker := "AM"
+			if strings.Contains(suffix, "p") {
+				marker = "PM"
+			}
+			return v[:len(v)-len(suffix)] + " " + marker
+		}
+	}
+	return v
+}
+
+// layoutTokens maps the date tokens users know from spreadsheets and moment.js to Go
+// layout elements, longest first.
+var layoutTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMMM", "January"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DDDD", "Monday"},
+	{"DDD", "Mon"},
+	{"DD", "02"},
+	{"D", "2"},
+	{"dddd", "Monday"},
+	{"ddd", "Mon"},
+	{"HH", "15"},
+	{"H", "15"},
+	{"hh", "03"},
+	{"h", "3"},
+	{"mm", "04"},
+	{"m", "4"},
+	{"ss", "05"},
+	{"s", "5"},
+	{"SSS", "000"},
+	{"A", "PM"},
+	{"a", "PM"},
+	{"ZZ", "-0700"},
+	{"Z", "-07:00"},
+}
+
+// parseTimeLayout turns a user supplied layout into a Go layout. The special layouts
+// "epoch", "epoch_ms" and "excel" select how numbers are read instead. Layouts already
+// written with Go's reference time are used as they are.
+func parseTimeLayout(layout string) (string, numericTime, error) {
+	layout = strings.TrimSpace(layout)
+	switch strings.ToLower(layout) {
+	case "":
+		return "", numericTimeAuto, fmt.Errorf("empty time layout")
+	case "epoch", "epoch_s", "unix":
+		return "", numericTimeEpochSeconds, nil
+	case "epoch_ms", "unix_ms":
+		return "", numericTimeEpochMillis, nil
+	case "excel", "serial":
+		return "", numericTimeExcel, nil
+	}
+
+	if strings.Contains(layout, "2006") || strings.Contains(layout, "Jan") {
+		return layout, numericTimeAuto, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(layout); {
+		if layout[i] == '[' {
+			end := strings.IndexByte(layout[i:], ']')
+			if end > 0 {
+				b.WriteString(layout[i+1 : i+end])
+				i += end + 1
+				continue
+			}
+		}
+		matched := false
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				b.WriteString(t.layout)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(layout[i])
+			i++
+		}
+	}
+
+	converted := b.String()
+	if !strings.ContainsAny(converted, "0123456789") {
+		return "", numericTimeAuto, fmt.Errorf("time layout %q has no date or time elements", layout)
+	}
+	return converted, numericTimeAuto, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestDetectDateOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []any
+		want   dateOrderDetection
+	}{
+		{"us dates", []any{"03/04/2025", "12/31/2025"}, dateOrderDetection{order: dateOrderMDY}},
+		{"uk dates", []any{"03/04/2025", "31/12/2025"}, dateOrderDetection{order: dateOrderDMY}},
+		{"ambiguous", []any{"03/04/2025", "05/06/2025"}, dateOrderDetection{order: dateOrderDMY, ambiguous: true}},
+		{"mixed", []any{"31/12/2025", "12/31/2025"}, dateOrderDetection{inconsistent: true}},
+		{"iso only", []any{"2025-04-03", nil}, dateOrderDetection{}},
+		{"uk dates with junk", []any{"03/04/2025", "n/a", "31/12/2025"}, dateOrderDetection{order: dateOrderDMY}},
+		{"junk only", []any{"n/a", "unknown"}, dateOrderDetection{}},
+	}
+
+	for _, tc := range tests {
+		if got := detectDateOrder(tc.values); got != tc.want {
+			t.Fatalf("%s: expected %+v got %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestBuildFieldDescriptorsDateOrder(t *testing.T) {
+	fields := []orcaField{
+		{Key: "scanned", Label: "Scanned", Format: "date"},
+		{Key: "shipped", Label: "Shipped On", Format: "date"},
+	}
+	rows := []map[string]any{
+		{"scanned": "03/04/2025", "shipped": "03/04/2025"},
+		{"scanned": "12/31/2025", "shipped": "05/06/2025"},
+	}
+	opts := parseOptions{fields: map[string]models.QueryFieldOptions{"shipped on": {DateOrder: "MDY"}}}
+
+	_, descMap := buildFieldDescriptors(fields, rows, opts)
+
+	normalized := normalizeRows(rows, descMap)
+	want := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	if got := normalized[0]["scanned"]; got != want {
+		t.Fatalf("expected auto-detected MDY date %s, got %v", want, got)
+	}
+	if got := normalized[0]["shipped"]; got != want {
+		t.Fatalf("expected per-field MDY override %s, got %v", want, got)
+	}
+
+	infos := buildFieldInfos([]fieldDescriptor{descMap["scanned"], descMap["shipped"]}, "")
+	if infos[0].DateOrder != "MDY" || infos[0].DateOrderAmbiguous {
+		t.Fatalf("unexpected date order metadata: %+v", infos[0])
+	}
+
+	_, descMap = buildFieldDescriptors(fields, rows[:1], parseOptions{})
+	if !descMap["shipped"].dateAmbiguous {
+		t.Fatal("expected ambiguity to be reported when no value disambiguates")
+	}
+}
+
+func TestParseValueToTimeFormats(t *testing.T) {
+	sept := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input any
+		want  time.Time
+	}{
+		{1756684800.0, sept},
+		{"1756684800000", sept},
+		{45901.0, sept},
+		{45901.5, sept.Add(12 * time.Hour)},
+		{"Mon, 01 Sep 2025 00:00:00 GMT", sept},
+		{"1 Sep 2025", sept},
+		{"September 1, 2025", sept},
+		{"2025-09-01 2:30 pm", sept.Add(14*time.Hour + 30*time.Minute)},
+		{"09/01/2025 8:05 AM", time.Date(2025, 1, 9, 8, 5, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		got, err := parseValueToTime(tc.input, parseOptions{})
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.input, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("%v: expected %s got %s", tc.input, tc.want, got)
+		}
+	}
+
+	if _, err := parseValueToTime(42.0, parseOptions{}); err == nil {
+		t.Fatal("expected small numbers not to be treated as timestamps")
+	}
+}
+
+func TestParseTimeLayout(t *testing.T) {
+	layout, _, err := parseTimeLayout("DD.MM.YYYY HH:mm:ss.SSS")
+	if err != nil || layout != "02.01.2006 15:04:05.000" {
+		t.Fatalf("unexpected layout %q (%v)", layout, err)
+	}
+
+	opts := parseOptions{fields: map[string]models.QueryFieldOptions{
+		"when": {Layouts: []string{"DD.MM.YYYY [at] h:mm A", "epoch_ms"}},
+	}}
+	opts = opts.forField(orcaField{Key: "when"})
+
+	got, err := parseValueToTime("01.09.2025 at 2:30 PM", opts)
+	if err != nil || !got.Equal(time.Date(2025, 9, 1, 14, 30, 0, 0, time.UTC)) {
+		t.Fatalf("custom layout: got %s (%v)", got, err)
+	}
+	if !isTimeValue("01.09.2025 at 2:30 PM", opts) {
+		t.Fatal("expected detection to honour custom layouts")
+	}
+	if got, err := parseValueToTime(1000.0, opts); err != nil || got.UnixMilli() != 1000 {
+		t.Fatalf("expected explicit epoch_ms to read small numbers, got %s (%v)", got, err)
+	}
+
+	if _, _, err := parseTimeLayout("[today]"); err == nil {
+		t.Fatal("expected error for layout without elements")
+	}
+}
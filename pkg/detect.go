@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultDetectionSampleSize = 500
+	defaultDetectionThreshold  = 0.98
+)
+
+// kindDetection is the outcome of detecting a column's kind from a sample of its values.
+// confidence is the share of sampled values that conform to kind.
+type kindDetection struct {
+	kind       fieldKind
+	confidence float64
+	sampled    int
+	dates      dateOrderDetection // set for auto-ordered time columns
+}
+
+// detectKind picks the kind that at least opts.threshold of a sample of key's non-null
+// values conform to. Columns whose metadata already names a kind other than string or
+// geo keep it when the same share of values conform; number, boolean and time fall back
+// to string otherwise.
+func detectKind(key string, rows []map[string]any, current fieldKind, opts parseOptions) kindDetection {
+	sample := sampleValues(key, rows, opts.sampleSize)
+	if len(sample) == 0 {
+		return kindDetection{kind: current}
+	}
+
+	var barcode, longBarcode, numeric, boolean, timeLike, geoLike, nested int
+	for _, val := range sample {
+		if isNestedValue(val) {
+			nested++
+		}
+		if ok, long := isBarcodeValue(val); ok {
+			barcode++
+			if long {
+				longBarcode++
+			}
+		}
+		if isNumericValue(val, opts) {
+			numeric++
+		}
+		if isBooleanValue(val) {
+			boolean++
+		}
+		if isTimeValue(val, opts) {
+			timeLike++
+		}
+		if isGeoValue(val) {
+			geoLike++
+		}
+	}
+
+	total := float64(len(sample))
+	threshold := opts.detectionThreshold()
+	share := func(n int) float64 { return float64(n) / total }
+	detected := func(kind fieldKind, n int) kindDetection {
+		return kindDetection{kind: kind, confidence: share(n), sampled: len(sample)}
+	}
+
+	if current != fieldKindString && current != fieldKindGeo {
+		conforming := len(sample)
+		switch current {
+		case fieldKindNumber:
+			conforming = numeric
+		case fieldKindBoolean:
+			conforming = boolean
+		case fieldKindTime:
+			conforming = timeLike
+		case fieldKindBarcode:
+			conforming = barcode
+		}
+		if current != fieldKindBarcode && share(conforming) < threshold {
+			// Cells that do not fit number, boolean or time are nulled, so metadata too
+			// many values contradict is kept as text. Barcode columns keep any text.
+			return detected(fieldKindString, len(sample))
+		}
+		return detected(current, conforming)
+	}
+
+	switch {
+	case opts.numericTime != numericTimeAuto && share(timeLike) >= threshold:
+		// Numeric layouts say the numbers are times, so they win over number and barcode.
+		return detected(fieldKindTime, timeLike)
+	case share(barcode) >= threshold && longBarcode > 0:
+		return detected(fieldKindBarcode, barcode)
+	case share(numeric) >= threshold:
+		return detected(fieldKindNumber, numeric)
+	case share(boolean) >= threshold:
+		return detected(fieldKindBoolean, boolean)
+	case share(timeLike) >= threshold && !detectDateOrder(sample).inconsistent:
+		return detected(fieldKindTime, timeLike)
+	case share(geoLike) >= threshold:
+		return detected(fieldKindGeo, geoLike)
+	case share(nested) >= threshold:
+		return detected(fieldKindJSON, nested)
+	}
+	if current == fieldKindGeo {
+		return detected(current, geoLike)
+	}
+	return detected(current, len(sample))
+}
+
+// sampleValues returns up to size non-null values of key, evenly spaced through rows so
+// a sheet sorted by entry date is sampled across its whole range. size <= 0 takes all.
+func sampleValues(key string, rows []map[string]any, size int) []any {
+	values := make([]any, 0, len(rows))
+	for _, row := range rows {
+		if val, ok := row[key]; ok && val != nil {
+			values = append(values, val)
+		}
+	}
+	if size <= 0 || len(values) <= size {
+		return values
+	}
+
+	sample := make([]any, size)
+	for i := range sample {
+		sample[i] = values[i*len(values)/size]
+	}
+	return sample
+}
+
+func (o parseOptions) detectionThreshold() float64 {
+	if o.threshold <= 0 || o.threshold > 1 {
+		return 1
+	}
+	return o.threshold
+}
+
+// detectionCacheKey identifies a detection by field and the options that change its
+// outcome, so a query with different layouts or separators detects afresh.
+func detectionCacheKey(f orcaField, opts parseOptions) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%d\x00%c\x00%q", f.Key, f.Type, f.Format, opts.dateOrder, opts.numericTime, opts.decimalSeparator, opts.layouts)
+}
+
+// conformsToKind reports whether a normalized value has the Go type of its column's kind.
+func conformsToKind(val any, kind fieldKind) bool {
+	switch kind {
+	case fieldKindNumber:
+		_, ok := val.(float64)
+		return ok
+	case fieldKindBoolean:
+		_, ok := val.(bool)
+		return ok
+	case fieldKindTime:
+		_, ok := val.(time.Time)
+		return ok
+	}
+	return true
+}
+
+// dropOffenders nulls normalized number, boolean and time values that did not parse,
+// recording how many each column had.
+func dropOffenders(rows []map[string]any, list []fieldDescriptor) ([]fieldDescriptor, map[string]fieldDescriptor) {
+	counts := make(map[string]int)
+	for _, row := range rows {
+		for _, desc := range list {
+			val, ok := row[desc.meta.Key]
+			if !ok || val == nil || conformsToKind(val, desc.kind) {
+				continue
+			}
+			row[desc.meta.Key] = nil
+			counts[desc.meta.Key]++
+		}
+	}
+
+	mapping := make(map[string]fieldDescriptor, len(list))
+	for idx, desc := range list {
+		desc.offenders = counts[desc.meta.Key]
+		if desc.offenders > 0 {
+			normalizationFailures.WithLabelValues(kindName(desc.kind)).Add(float64(desc.offenders))
+		}
+		list[idx] = desc
+		mapping[desc.meta.Key] = desc
+	}
+	return list, mapping
+}
+
+// cachedDetections returns a copy of the kind detections cached with sheetID's fields.
+func (i *orcaInstance) cachedDetections(sheetID string) map[string]kindDetection {
+	i.fieldCacheMu.RLock()
+	defer i.fieldCacheMu.RUnlock()
+
+	cached := make(map[string]kindDetection)
+	if entry, ok := i.fieldCache[sheetID]; ok && time.Since(entry.fetchedAt) < fieldCacheTTL {
+		for key, detection := range entry.detections {
+			cached[key] = detection
+		}
+	}
+	return cached
+}
+
+// storeDetections caches detections until sheetID's field metadata expires.
+func (i *orcaInstance) storeDetections(sheetID string, detections map[string]kindDetection) {
+	i.fieldCacheMu.Lock()
+	defer i.fieldCacheMu.Unlock()
+
+	entry, ok := i.fieldCache[sheetID]
+	if !ok || time.Since(entry.fetchedAt) >= fieldCacheTTL {
+		return
+	}
+	entry.detections = detections
+	i.fieldCache[sheetID] = entry
+}
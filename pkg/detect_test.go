@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestDetectKindThreshold(t *testing.T) {
+	rows := make([]map[string]any, 0, 100)
+	for i := 0; i < 99; i++ {
+		rows = append(rows, map[string]any{"Qty": fmt.Sprint(i)})
+	}
+	rows = append(rows, map[string]any{"Qty": "12 pcs?"})
+
+	strict := detectKind("Qty", rows, fieldKindString, parseOptions{})
+	if strict.kind != fieldKindString {
+		t.Fatalf("expected one typo to keep a strict column as string, got %v", strict.kind)
+	}
+
+	tolerant := detectKind("Qty", rows, fieldKindString, parseOptions{threshold: 0.98})
+	if tolerant.kind != fieldKindNumber || tolerant.confidence != 0.99 || tolerant.sampled != 100 {
+		t.Fatalf("unexpected detection %+v", tolerant)
+	}
+
+	descList, descMap := buildFieldDescriptors([]orcaField{{Key: "Qty"}}, rows, parseOptions{threshold: 0.98})
+	normalized := normalizeRows(rows, descMap)
+	descList, _ = dropOffenders(normalized, descList)
+	if normalized[99]["Qty"] != nil || descList[0].offenders != 1 {
+		t.Fatalf("expected offender to be nulled and counted, got %v (%d)", normalized[99]["Qty"], descList[0].offenders)
+	}
+
+	fields := buildFieldInfos(descList, "")
+	if fields[0].Confidence == nil || *fields[0].Confidence != 0.99 || fields[0].Offenders != 1 {
+		t.Fatalf("unexpected field info %+v", fields[0])
+	}
+}
+
+func TestDetectKindNumericLayouts(t *testing.T) {
+	rows := []map[string]any{
+		{"Scanned": 1756684800000.0},
+		{"Scanned": "1756684800000"},
+		{"Scanned": " 1756771200000 "},
+	}
+	opts := parseOptions{fields: map[string]models.QueryFieldOptions{
+		"Scanned": {Layouts: []string{"epoch_ms"}},
+	}}
+
+	descList, descMap := buildFieldDescriptors([]orcaField{{Key: "Scanned"}}, rows, opts)
+	if descList[0].kind != fieldKindTime {
+		t.Fatalf("expected epoch_ms layout to detect time, got %v", descList[0].kind)
+	}
+	normalized := normalizeRows(rows, descMap)
+	want := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	if ts, ok := normalized[1]["Scanned"].(time.Time); !ok || !ts.Equal(want) {
+		t.Fatalf("unexpected normalized value %v", normalized[1]["Scanned"])
+	}
+
+	if kind := detectKind("Scanned", rows, fieldKindString, parseOptions{}).kind; kind != fieldKindNumber {
+		t.Fatalf("expected numbers without a layout to stay numbers, got %v", kind)
+	}
+}
+
+func TestDetectKindDatesWithJunk(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	iso := make([]map[string]any, 0, 201)
+	dmy := make([]map[string]any, 0, 201)
+	for n := 0; n < 200; n++ {
+		d := day.AddDate(0, 0, n)
+		iso = append(iso, map[string]any{"When": d.Format("2006-01-02")})
+		dmy = append(dmy, map[string]any{"When": d.Format("02/01/2006")})
+	}
+	iso = append(iso, map[string]any{"When": "n/a"})
+	dmy = append(dmy, map[string]any{"When": "n/a"})
+
+	opts := parseOptions{threshold: 0.98}
+	for name, rows := range map[string][]map[string]any{"iso": iso, "dmy": dmy} {
+		detection := detectKind("When", rows, fieldKindString, opts)
+		if detection.kind != fieldKindTime || detection.confidence != 200.0/201 {
+			t.Fatalf("%s: expected time despite one junk cell, got %+v", name, detection)
+		}
+	}
+
+	_, descMap := buildFieldDescriptors([]orcaField{{Key: "When"}}, dmy, opts)
+	if desc := descMap["When"]; desc.opts.dateOrder != dateOrderDMY || desc.dateAmbiguous {
+		t.Fatalf("expected DMY order from the dates alone, got %+v", desc)
+	}
+}
+
+func TestDetectKindMetadataThreshold(t *testing.T) {
+	rows := []map[string]any{{"Qty": "12"}, {"Qty": "about ten"}, {"Qty": "several"}, {"Qty": "n/a"}}
+	opts := parseOptions{threshold: 0.98}
+
+	if detection := detectKind("Qty", rows, fieldKindNumber, opts); detection.kind != fieldKindString {
+		t.Fatalf("expected mostly-text number metadata to fall back to string, got %+v", detection)
+	}
+
+	descList, descMap := buildFieldDescriptors([]orcaField{{Key: "Qty", Type: "number"}}, rows, opts)
+	normalized := normalizeRows(rows, descMap)
+	descList, _ = dropOffenders(normalized, descList)
+	if normalized[1]["Qty"] != "about ten" || descList[0].offenders != 0 {
+		t.Fatalf("expected text values kept, got %v (%d offenders)", normalized[1]["Qty"], descList[0].offenders)
+	}
+
+	rows[1]["Qty"] = "10"
+	if detection := detectKind("Qty", rows[:2], fieldKindNumber, opts); detection.kind != fieldKindNumber || detection.confidence != 1 {
+		t.Fatalf("expected conforming metadata kind to be kept, got %+v", detection)
+	}
+}
+
+func TestSampleValues(t *testing.T) {
+	rows := make([]map[string]any, 0, 10)
+	for i := 0; i < 10; i++ {
+		rows = append(rows, map[string]any{"N": i})
+	}
+	rows = append(rows, map[string]any{"N": nil})
+
+	sample := sampleValues("N", rows, 5)
+	want := []any{0, 2, 4, 6, 8}
+	if fmt.Sprint(sample) != fmt.Sprint(want) {
+		t.Fatalf("expected evenly spaced sample %v, got %v", want, sample)
+	}
+	if got := sampleValues("N", rows, 0); len(got) != 10 {
+		t.Fatalf("expected all non-null values, got %v", got)
+	}
+}
+
+func TestDetectionCache(t *testing.T) {
+	inst := &orcaInstance{fieldCache: map[string]fieldCacheEntry{
+		"sheet": {fields: []orcaField{{Key: "Code"}}, fetchedAt: time.Now()},
+	}}
+	fields := []orcaField{{Key: "Code"}}
+
+	detections := inst.cachedDetections("sheet")
+	numbers := []map[string]any{{"Code": "1"}, {"Code": "2"}}
+	if _, descMap := buildFieldDescriptorsCached(fields, numbers, parseOptions{}, detections); descMap["Code"].kind != fieldKindNumber {
+		t.Fatalf("expected number, got %v", descMap["Code"].kind)
+	}
+	inst.storeDetections("sheet", detections)
+
+	text := []map[string]any{{"Code": "A"}, {"Code": "B"}}
+	_, descMap := buildFieldDescriptorsCached(fields, text, parseOptions{}, inst.cachedDetections("sheet"))
+	if descMap["Code"].kind != fieldKindNumber {
+		t.Fatalf("expected cached decision to be reused, got %v", descMap["Code"].kind)
+	}
+
+	_, descMap = buildFieldDescriptorsCached(fields, text, parseOptions{decimalSeparator: ','}, inst.cachedDetections("sheet"))
+	if descMap["Code"].kind != fieldKindString {
+		t.Fatalf("expected different options to detect afresh, got %v", descMap["Code"].kind)
+	}
+}
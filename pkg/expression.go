@@ -0,0 +1,1008 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+// Computed columns are defined with a small expression language evaluated per row:
+//
+//	Quantity * [Unit Price]
+//	if(Stock < 10, "low", "ok")
+//	round(datediff("day", LastScanned, now()), 0)
+//
+// Column names are bare identifiers or wrapped in [brackets] when they contain spaces,
+// and resolve like the time field does (key, label, then canonicalized name). Arithmetic,
+// + included, is numeric and reads text that parses as a number; & joins text. Values that
+// cannot be evaluated for a row (type mismatches, division by zero) become null.
+
+type exprNode interface {
+	eval(row map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+	text  string
+}
+
+type columnNode struct {
+	key  string
+	opts parseOptions // the column's own options
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+	opts    parseOptions
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+	opts        parseOptions // reads string operands as times and numbers
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+	opts parseOptions
+}
+
+type exprFunc struct {
+	minArgs, maxArgs int // maxArgs < 0 means variadic
+	call             func(args []any, opts parseOptions) (any, error)
+}
+
+var exprFuncs map[string]exprFunc
+
+func init() {
+	exprFuncs = map[string]exprFunc{
+		"coalesce": {1, -1, exprCoalesce},
+		"round":    {1, 2, exprRound},
+		"floor":    {1, 1, numericFunc(math.Floor)},
+		"ceil":     {1, 1, numericFunc(math.Ceil)},
+		"abs":      {1, 1, numericFunc(math.Abs)},
+		"min":      {1, -1, exprMin},
+		"max":      {1, -1, exprMax},
+		"concat":   {1, -1, exprConcat},
+		"upper":    {1, 1, stringFunc(strings.ToUpper)},
+		"lower":    {1, 1, stringFunc(strings.ToLower)},
+		"trim":     {1, 1, stringFunc(strings.TrimSpace)},
+		"len":      {1, 1, exprLen},
+		"now":      {0, 0, func(_ []any, opts parseOptions) (any, error) { return time.Now().In(opts.loc()), nil }},
+		"today":    {0, 0, exprToday},
+		"datediff": {3, 3, exprDateDiff},
+	}
+}
+
+// computedColumn is a parsed models.ComputedColumn.
+type computedColumn struct {
+	key      string
+	expr     exprNode
+	decimals *int
+}
+
+// applyComputedColumns evaluates each computed column against the normalized rows in
+// order, so later columns may reference earlier ones, and records a descriptor whose kind
+// is inferred from the results. String values compared with times are read with opts.
+func applyComputedColumns(sheet sheetData, specs []models.ComputedColumn, opts parseOptions) (sheetData, error) {
+	if len(specs) == 0 {
+		return sheet, nil
+	}
+
+	descList := append([]fieldDescriptor(nil), sheet.descList...)
+	descMap := make(map[string]fieldDescriptor, len(sheet.descMap)+len(specs))
+	for key, desc := range sheet.descMap {
+		descMap[key] = desc
+	}
+
+	rows := make([]map[string]any, len(sheet.rows))
+	for idx, row := range sheet.rows {
+		out := make(map[string]any, len(row)+len(specs))
+		for key, val := range row {
+			out[key] = val
+		}
+		rows[idx] = out
+	}
+
+	for _, spec := range specs {
+		col, err := parseComputedColumn(spec, descList, rows, opts)
+		if err != nil {
+			return sheetData{}, err
+		}
+
+		values := make([]any, len(rows))
+		for idx, row := range rows {
+			val, err := col.expr.eval(row)
+			if err != nil {
+				val = nil
+			}
+			if f, ok := val.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				val = nil
+			}
+			if col.decimals != nil {
+				if f, ok := val.(float64); ok {
+					val = roundTo(f, *col.decimals)
+				}
+			}
+			values[idx] = val
+			row[col.key] = val
+		}
+
+		desc := fieldDescriptor{
+			meta: orcaField{Key: col.key, Label: col.key, Type: "computed"},
+			kind: kindOfValues(values),
+		}
+		if desc.kind == fieldKindNumber {
+			decimals, ok := 0, false
+			if col.decimals != nil {
+				decimals, ok = *col.decimals, true
+			} else {
+				decimals, ok = exprDecimals(col.expr, descMap)
+			}
+			if ok && decimals > 0 {
+				desc.decimals = decimals
+				desc.hasDecimals = true
+			}
+		}
+
+		if _, exists := descMap[col.key]; exists {
+			for idx := range descList {
+				if descList[idx].meta.Key == col.key {
+					descList[idx] = desc
+				}
+			}
+		} else {
+			descList = append(descList, desc)
+		}
+		descMap[col.key] = desc
+	}
+
+	return sheetData{
+		sheetID:  sheet.sheetID,
+		rows:     rows,
+		descList: descList,
+		descMap:  descMap,
+		columns:  appendDescriptorColumns(sheet.columns, descList),
+	}, nil
+}
+
+func parseComputedColumn(spec models.ComputedColumn, descList []fieldDescriptor, rows []map[string]any, opts parseOptions) (computedColumn, error) {
+	name := normalizeFieldKey(spec.Name)
+	if name == "" {
+		return computedColumn{}, newRequestError("computed column name is required")
+	}
+
+	expr, err := parseExpression(spec.Expression, func(ref string) (string, bool) {
+		return resolveFieldKey(ref, descList, rows)
+	}, opts)
+	if err != nil {
+		return computedColumn{}, newRequestError("computed column %q: %v", name, err)
+	}
+
+	return computedColumn{key: name, expr: expr, decimals: spec.Decimals}, nil
+}
+
+// kindOfValues infers the field kind shared by every non-null value.
+func kindOfValues(values []any) fieldKind {
+	kind := fieldKindString
+	seen := false
+	for _, val := range values {
+		var k fieldKind
+		switch val.(type) {
+		case nil:
+			continue
+		case float64:
+			k = fieldKindNumber
+		case bool:
+			k = fieldKindBoolean
+		case time.Time:
+			k = fieldKindTime
+		default:
+			return fieldKindString
+		}
+		if seen && k != kind {
+			return fieldKindString
+		}
+		kind, seen = k, true
+	}
+	return kind
+}
+
+// exprDecimals statically infers how many decimals a numeric expression produces.
+func exprDecimals(node exprNode, descriptors map[string]fieldDescriptor) (int, bool) {
+	switch n := node.(type) {
+	case literalNode:
+		if _, ok := n.value.(float64); ok {
+			return decimalsInComponent(n.text), true
+		}
+	case columnNode:
+		if desc, ok := descriptors[n.key]; ok && desc.kind == fieldKindNumber {
+			return desc.decimals, true
+		}
+	case unaryNode:
+		return exprDecimals(n.operand, descriptors)
+	case binaryNode:
+		l, lok := exprDecimals(n.left, descriptors)
+		r, rok := exprDecimals(n.right, descriptors)
+		if !lok || !rok {
+			return 0, false
+		}
+		switch n.op {
+		case "+", "-":
+			return max(l, r), true
+		case "*":
+			return l + r, true
+		}
+	case callNode:
+		switch n.name {
+		case "round":
+			if len(n.args) == 1 {
+				return 0, true
+			}
+			if lit, ok := n.args[1].(literalNode); ok {
+				if f, ok := lit.value.(float64); ok && f >= 0 {
+					return int(f), true
+				}
+			}
+		case "floor", "ceil", "datediff", "len":
+			return 0, true
+		case "abs":
+			return exprDecimals(n.args[0], descriptors)
+		case "min", "max", "coalesce":
+			best := 0
+			for _, arg := range n.args {
+				d, ok := exprDecimals(arg, descriptors)
+				if !ok {
+					return 0, false
+				}
+				best = max(best, d)
+			}
+			return best, true
+		}
+	}
+	return 0, false
+}
+
+func (n literalNode) eval(map[string]any) (any, error) { return n.value, nil }
+
+func (n columnNode) eval(row map[string]any) (any, error) {
+	val := row[n.key]
+	if num, ok := val.(json.Number); ok {
+		if f, err := num.Float64(); err == nil {
+			return f, nil
+		}
+	}
+	switch val.(type) {
+	case float32, int, int32, int64, uint, uint32, uint64:
+		return toFloat64(val), nil
+	}
+	return val, nil
+}
+
+func (n unaryNode) eval(row map[string]any) (any, error) {
+	val, err := n.operand.eval(row)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	switch n.op {
+	case "-":
+		f, ok := exprNumber(val, n.opts)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %v", val)
+		}
+		return -f, nil
+	case "not":
+		return !exprTruthy(val), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func (n binaryNode) eval(row map[string]any) (any, error) {
+	left, err := n.left.eval(row)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "and":
+		if !exprTruthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		return exprTruthy(right), nil
+	case "or":
+		if exprTruthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		return exprTruthy(right), nil
+	}
+
+	right, err := n.right.eval(row)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "&":
+		return exprString(left) + exprString(right), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return exprCompare(n.op, left, right, n.opts)
+	}
+
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	l, lok := exprNumber(left, n.opts)
+	r, rok := exprNumber(right, n.opts)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s expects numbers", n.op)
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func (n callNode) eval(row map[string]any) (any, error) {
+	if n.name == "if" {
+		cond, err := n.args[0].eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if exprTruthy(cond) {
+			return n.args[1].eval(row)
+		}
+		if len(n.args) > 2 {
+			return n.args[2].eval(row)
+		}
+		return nil, nil
+	}
+
+	args := make([]any, len(n.args))
+	for idx, arg := range n.args {
+		val, err := arg.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		args[idx] = val
+	}
+	return exprFuncs[n.name].call(args, n.opts)
+}
+
+// exprCompare compares left and right, reading a string compared with a time with opts
+// so that literals without a zone are in the query's timezone.
+func exprCompare(op string, left, right any, opts parseOptions) (any, error) {
+	if left == nil || right == nil {
+		switch op {
+		case "=":
+			return left == nil && right == nil, nil
+		case "!=":
+			return (left == nil) != (right == nil), nil
+		}
+		return nil, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case time.Time:
+		r, err := parseValueToTime(right, opts)
+		if err != nil {
+			return nil, err
+		}
+		cmp = l.Compare(r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok || (op != "=" && op != "!=") {
+			return nil, fmt.Errorf("cannot compare %v and %v", left, right)
+		}
+		cmp = 1
+		if l == r {
+			cmp = 0
+		}
+	default:
+		lf, lok := exprNumber(left, opts)
+		rf, rok := exprNumber(right, opts)
+		if lok && rok {
+			switch {
+			case lf < rf:
+				cmp = -1
+			case lf > rf:
+				cmp = 1
+			}
+		} else if rt, ok := right.(time.Time); ok {
+			lt, err := parseValueToTime(left, opts)
+			if err != nil {
+				return nil, err
+			}
+			cmp = lt.Compare(rt)
+		} else {
+			cmp = strings.Compare(exprString(left), exprString(right))
+		}
+	}
+
+	switch op {
+	case "=":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// exprNumber reads val as a number; strings parse like sheet cells with opts, so "1,5"
+// is 1.5 under a comma decimal separator.
+func exprNumber(val any, opts parseOptions) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		parsed, ok := parseNumberString(v, opts.decimalSeparator)
+		return parsed.value, ok
+	}
+	return 0, false
+}
+
+func exprString(val any) string {
+	if val == nil {
+		return ""
+	}
+	return fmt.Sprint(stringifyValue(val))
+}
+
+func exprTruthy(val any) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func numericFunc(fn func(float64) float64) func([]any, parseOptions) (any, error) {
+	return func(args []any, opts parseOptions) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		f, ok := exprNumber(args[0], opts)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %v", args[0])
+		}
+		return fn(f), nil
+	}
+}
+
+func stringFunc(fn func(string) string) func([]any, parseOptions) (any, error) {
+	return func(args []any, _ parseOptions) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return fn(exprString(args[0])), nil
+	}
+}
+
+func exprCoalesce(args []any, _ parseOptions) (any, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+func exprRound(args []any, opts parseOptions) (any, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	f, ok := exprNumber(args[0], opts)
+	if !ok {
+		return nil, fmt.Errorf("round expects a number, got %v", args[0])
+	}
+	places := 0
+	if len(args) > 1 {
+		p, ok := exprNumber(args[1], opts)
+		if !ok {
+			return nil, fmt.Errorf("round expects a number of places, got %v", args[1])
+		}
+		places = int(p)
+	}
+	return roundTo(f, places), nil
+}
+
+func roundTo(f float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(f*factor) / factor
+}
+
+func exprMin(args []any, opts parseOptions) (any, error) {
+	return exprExtreme(args, opts, func(a, b float64) bool { return a < b })
+}
+
+func exprMax(args []any, opts parseOptions) (any, error) {
+	return exprExtreme(args, opts, func(a, b float64) bool { return a > b })
+}
+
+func exprExtreme(args []any, opts parseOptions, better func(a, b float64) bool) (any, error) {
+	var best *float64
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		f, ok := exprNumber(arg, opts)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %v", arg)
+		}
+		if best == nil || better(f, *best) {
+			best = &f
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return *best, nil
+}
+
+func exprConcat(args []any, _ parseOptions) (any, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(exprString(arg))
+	}
+	return b.String(), nil
+}
+
+func exprLen(args []any, _ parseOptions) (any, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return float64(len([]rune(exprString(args[0])))), nil
+}
+
+func exprToday(_ []any, opts parseOptions) (any, error) {
+	now := time.Now().In(opts.loc())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+}
+
+// exprDateDiff returns the number of whole units between start and end, negative when
+// end is before start. String arguments are read as times with opts.
+func exprDateDiff(args []any, opts parseOptions) (any, error) {
+	if args[1] == nil || args[2] == nil {
+		return nil, nil
+	}
+	start, err := parseValueToTime(args[1], opts)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseValueToTime(args[2], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(exprString(args[0]))), "s")
+	diff := end.Sub(start)
+	switch unit {
+	case "second":
+		return math.Trunc(diff.Seconds()), nil
+	case "minute":
+		return math.Trunc(diff.Minutes()), nil
+	case "hour":
+		return math.Trunc(diff.Hours()), nil
+	case "day":
+		return math.Trunc(diff.Hours() / 24), nil
+	case "week":
+		return math.Trunc(diff.Hours() / (24 * 7)), nil
+	case "month":
+		return float64(wholeMonths(start, end)), nil
+	case "year":
+		return float64(wholeMonths(start, end) / 12), nil
+	}
+	return nil, fmt.Errorf("unknown datediff unit %q", args[0])
+}
+
+func wholeMonths(start, end time.Time) int {
+	if end.Before(start) {
+		return -wholeMonths(end, start)
+	}
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if months > 0 && start.AddDate(0, months, 0).After(end) {
+		months--
+	}
+	return months
+}
+
+type exprToken struct {
+	kind string // "num", "str", "ident", "column", "op", "eof"
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	tokens  []exprToken
+	pos     int
+	resolve func(string) (string, bool)
+	opts    parseOptions
+}
+
+// parseExpression parses src into an expression tree, resolving column references with
+// resolve. Strings read as times or numbers use opts, or the options a referenced
+// column overrides in opts.
+func parseExpression(src string, resolve func(string) (string, bool), opts parseOptions) (exprNode, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	tokens, err := tokenizeExpression(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens, resolve: resolve, opts: opts}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return node, nil
+}
+
+func tokenizeExpression(src string) ([]exprToken, error) {
+	runes := []rune(src)
+	tokens := make([]exprToken, 0)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "num", text: string(runes[start:i]), pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: "str", text: b.String(), pos: start})
+		case r == '[' || r == '`':
+			closing := ']'
+			if r == '`' {
+				closing = '`'
+			}
+			start := i
+			i++
+			for i < len(runes) && runes[i] != closing {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated column reference at position %d", start)
+			}
+			tokens = append(tokens, exprToken{kind: "column", text: string(runes[start+1 : i]), pos: start})
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", text: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<>", "<=", ">=", "&&", "||":
+				tokens = append(tokens, exprToken{kind: "op", text: two, pos: start})
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("+-*/%&()<>=!,", r) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, start)
+			}
+			tokens = append(tokens, exprToken{kind: "op", text: string(r), pos: start})
+			i++
+		}
+	}
+
+	return append(tokens, exprToken{kind: "eof", pos: len(runes)}), nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is an operator or keyword in ops.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != "op" && tok.kind != "ident" {
+		return "", false
+	}
+	for _, op := range ops {
+		if strings.EqualFold(tok.text, op) {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "or", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "and", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "not", operand: operand, opts: operandOpts(p.opts, operand)}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<>", "<=", ">=", "=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	switch op {
+	case "==":
+		op = "="
+	case "<>":
+		op = "!="
+	}
+	right, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, left: left, right: right, opts: operandOpts(p.opts, left, right)}, nil
+}
+
+func (p *exprParser) parseConcat() (exprNode, error) {
+	return p.parseBinary([]string{"&"}, p.parseAdditive)
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	return p.parseBinary([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	return p.parseBinary([]string{"*", "/", "%"}, p.parseUnary)
+}
+
+func (p *exprParser) parseBinary(ops []string, operand func() (exprNode, error)) (exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.peek().kind != "op" {
+			return left, nil
+		}
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right, opts: operandOpts(p.opts, left, right)}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek().kind == "op" {
+		if _, ok := p.accept("-"); ok {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return unaryNode{op: "-", operand: operand, opts: operandOpts(p.opts, operand)}, nil
+		}
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "num":
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literalNode{value: f, text: tok.text}, nil
+	case "str":
+		return literalNode{value: tok.text, text: tok.text}, nil
+	case "column":
+		return p.column(tok)
+	case "ident":
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return literalNode{value: true, text: tok.text}, nil
+		case "false":
+			return literalNode{value: false, text: tok.text}, nil
+		case "null":
+			return literalNode{value: nil, text: tok.text}, nil
+		}
+		if next := p.peek(); next.kind == "op" && next.text == "(" {
+			return p.call(tok)
+		}
+		return p.column(tok)
+	case "op":
+		if tok.text == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("expected ) at position %d", p.peek().pos)
+			}
+			return node, nil
+		}
+	case "eof":
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *exprParser) column(tok exprToken) (exprNode, error) {
+	key, ok := p.resolve(normalizeFieldKey(tok.text))
+	if !ok {
+		return nil, fmt.Errorf("unknown column %q at position %d", tok.text, tok.pos)
+	}
+	return columnNode{key: key, opts: p.opts.forField(orcaField{Key: key})}, nil
+}
+
+// operandOpts returns the options of the first column among operands, so strings read
+// from a column parse with its date order and decimal separator, or else opts.
+func operandOpts(opts parseOptions, operands ...exprNode) parseOptions {
+	for _, operand := range operands {
+		if col, ok := operand.(columnNode); ok {
+			return col.opts
+		}
+	}
+	return opts
+}
+
+func (p *exprParser) call(tok exprToken) (exprNode, error) {
+	name := strings.ToLower(tok.text)
+	p.next() // (
+
+	args := make([]exprNode, 0)
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			return nil, fmt.Errorf("expected , or ) at position %d", p.peek().pos)
+		}
+	}
+
+	minArgs, maxArgs := 2, 3
+	if name != "if" {
+		fn, ok := exprFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at position %d", tok.text, tok.pos)
+		}
+		minArgs, maxArgs = fn.minArgs, fn.maxArgs
+	}
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s at position %d", name, tok.pos)
+	}
+
+	return callNode{name: name, args: args, opts: operandOpts(p.opts, args...)}, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestParseExpressionEval(t *testing.T) {
+	row := map[string]any{
+		"Quantity":    4.0,
+		"Unit Price":  2.5,
+		"Name":        "Widget",
+		"Code":        "12",
+		"LastScanned": time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+		"Checked":     time.Date(2025, 9, 11, 12, 0, 0, 0, time.UTC),
+		"Empty":       nil,
+	}
+	resolve := func(name string) (string, bool) {
+		return resolveFieldKey(name, nil, []map[string]any{row})
+	}
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"Quantity * [Unit Price]", 10.0},
+		{"-Quantity + 2 * 3", 2.0},
+		{"(Quantity + 1) % 3", 2.0},
+		{`Name & " x" & Quantity`, "Widget x4"},
+		{`Name & "!"`, "Widget!"},
+		{`Name + "!"`, nil},
+		{`Code + 1`, 13.0},
+		{`if(Quantity > 3 and not (Name = "Gadget"), "many", "few")`, "many"},
+		{"round(10 / 3, 2)", 3.33},
+		{`datediff("days", LastScanned, Checked)`, 10.0},
+		{`datediff("month", Checked, LastScanned)`, 0.0},
+		{"coalesce(Empty, Quantity)", 4.0},
+		{"Empty * 2", nil},
+		{"max(1, Quantity, 3)", 4.0},
+		{"upper(Name)", "WIDGET"},
+		{"Quantity / 0", nil},
+	}
+
+	for _, tc := range tests {
+		node, err := parseExpression(tc.expr, resolve, parseOptions{})
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", tc.expr, err)
+		}
+		got, err := node.eval(row)
+		if err != nil {
+			got = nil
+		}
+		if got != tc.want {
+			t.Fatalf("%s: expected %#v got %#v", tc.expr, tc.want, got)
+		}
+	}
+
+	for _, bad := range []string{"", "Quantity *", "Missing + 1", "nope(1)", "round(1, 2, 3)", `"open`} {
+		if _, err := parseExpression(bad, resolve, parseOptions{}); err == nil {
+			t.Fatalf("%q: expected parse error", bad)
+		}
+	}
+}
+
+func TestExpressionTimeZone(t *testing.T) {
+	row := map[string]any{"seen": time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)}
+	resolve := func(string) (string, bool) { return "seen", true }
+	eastern := parseOptions{location: time.FixedZone("UTC-5", -5*3600)}
+
+	tests := []struct {
+		expr string
+		opts parseOptions
+		want any
+	}{
+		{`LastScanned > "2025-09-01 08:00"`, parseOptions{}, true},
+		{`LastScanned > "2025-09-01 08:00"`, eastern, false},
+		{`datediff("hour", "2025-09-01 08:00", LastScanned)`, parseOptions{}, 2.0},
+		{`datediff("hour", "2025-09-01 08:00", LastScanned)`, eastern, -3.0},
+	}
+	for _, tc := range tests {
+		node, err := parseExpression(tc.expr, resolve, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", tc.expr, err)
+		}
+		if got, err := node.eval(row); err != nil || got != tc.want {
+			t.Fatalf("%s in %v: expected %#v got %#v (%v)", tc.expr, tc.opts.loc(), tc.want, got, err)
+		}
+	}
+}
+
+func TestExpressionNumberLocale(t *testing.T) {
+	row := map[string]any{"Price": "1,500", "Qty": "2"}
+	resolve := func(name string) (string, bool) { return name, true }
+	comma := parseOptions{fields: map[string]models.QueryFieldOptions{"Price": {DecimalSeparator: ","}}}
+
+	tests := []struct {
+		expr string
+		opts parseOptions
+		want any
+	}{
+		{"Price * Qty", parseOptions{}, 3000.0},
+		{"Price * Qty", comma, 3.0},
+		{"-Price", comma, -1.5},
+		{"round(Price, 1)", comma, 1.5},
+		{"Price > 2", comma, false},
+		{`"2,5" + 1`, parseOptions{decimalSeparator: ','}, 3.5},
+	}
+	for _, tc := range tests {
+		node, err := parseExpression(tc.expr, resolve, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", tc.expr, err)
+		}
+		if got, err := node.eval(row); err != nil || got != tc.want {
+			t.Fatalf("%s: expected %#v got %#v (%v)", tc.expr, tc.want, got, err)
+		}
+	}
+}
+
+func TestExpressionTodayTimeZone(t *testing.T) {
+	kiritimati := time.FixedZone("UTC+14", 14*3600)
+	opts := parseOptions{location: kiritimati}
+	resolve := func(string) (string, bool) { return "", false }
+
+	before := time.Now().In(kiritimati)
+	values := map[string]any{}
+	for _, expr := range []string{"today()", "now()"} {
+		node, err := parseExpression(expr, resolve, opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", expr, err)
+		}
+		if values[expr], err = node.eval(nil); err != nil {
+			t.Fatalf("%s: unexpected eval error: %v", expr, err)
+		}
+	}
+	after := time.Now().In(kiritimati)
+
+	now, ok := values["now()"].(time.Time)
+	if !ok || now.Location() != kiritimati {
+		t.Fatalf("expected now() in %v, got %#v", kiritimati, values["now()"])
+	}
+	today, ok := values["today()"].(time.Time)
+	if !ok || today.Location() != kiritimati || today.Hour() != 0 {
+		t.Fatalf("expected midnight in %v, got %#v", kiritimati, values["today()"])
+	}
+	if today.YearDay() != before.YearDay() && today.YearDay() != after.YearDay() {
+		t.Fatalf("expected today() to follow the %v calendar, got %v at %v", kiritimati, today, before)
+	}
+}
+
+func TestApplyComputedColumns(t *testing.T) {
+	sheet := sheetData{
+		descList: []fieldDescriptor{
+			{meta: orcaField{Key: "qty", Label: "Quantity"}, kind: fieldKindNumber},
+			{meta: orcaField{Key: "price", Label: "Unit Price"}, kind: fieldKindNumber, decimals: 2, hasDecimals: true},
+		},
+		rows: []map[string]any{
+			{"qty": 3.0, "price": 1.25},
+			{"qty": nil, "price": 2.5},
+		},
+	}
+	sheet.descMap = map[string]fieldDescriptor{"qty": sheet.descList[0], "price": sheet.descList[1]}
+
+	got, err := applyComputedColumns(sheet, []models.ComputedColumn{
+		{Name: "Total", Expression: "Quantity * [Unit Price]"},
+		{Name: "Big", Expression: "Total > 3"},
+	}, parseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.rows[0]["Total"] != 3.75 || got.rows[1]["Total"] != nil {
+		t.Fatalf("unexpected totals: %v", got.rows)
+	}
+	total := got.descMap["Total"]
+	if total.kind != fieldKindNumber || total.decimals != 2 {
+		t.Fatalf("expected numeric total with 2 decimals, got %+v", total)
+	}
+	if got.descMap["Big"].kind != fieldKindBoolean || got.rows[0]["Big"] != true {
+		t.Fatalf("expected boolean column referencing earlier computed column, got %+v", got.rows[0])
+	}
+	if _, ok := sheet.rows[0]["Total"]; ok {
+		t.Fatal("expected input rows to be left untouched")
+	}
+
+	if _, err := applyComputedColumns(sheet, []models.ComputedColumn{{Name: "Bad", Expression: "nope +"}}, parseOptions{}); statusFromError(err) != http.StatusBadRequest {
+		t.Fatalf("expected bad request for invalid expression, got %v", err)
+	}
+}
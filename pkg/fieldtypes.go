@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// parseFieldKind reads a type name from a query's fieldTypes.
+func parseFieldKind(raw string) (fieldKind, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "string", "text":
+		return fieldKindString, nil
+	case "number", "numeric":
+		return fieldKindNumber, nil
+	case "boolean", "bool":
+		return fieldKindBoolean, nil
+	case "time", "date", "datetime":
+		return fieldKindTime, nil
+	case "geo", "gps", "location":
+		return fieldKindGeo, nil
+	case "enum":
+		return fieldKindEnum, nil
+	case "json":
+		return fieldKindJSON, nil
+	case "barcode":
+		return fieldKindBarcode, nil
+	default:
+		return fieldKindString, fmt.Errorf("unknown type %q (use string, number, boolean, time, geo, enum, json or barcode)", raw)
+	}
+}
+
+// barcodeNameWords are the words that mark a column as holding barcodes or product
+// codes, whose leading zeros matter more than their numeric value.
+var barcodeNameWords = map[string]struct{}{
+	"barcode": {}, "barcodes": {}, "sku": {}, "ean": {}, "ean8": {}, "ean13": {},
+	"upc": {}, "upca": {}, "upce": {}, "gtin": {}, "gtin8": {}, "gtin12": {},
+	"gtin13": {}, "gtin14": {}, "isbn": {}, "isbn10": {}, "isbn13": {}, "asin": {},
+	"plu": {}, "sscc": {},
+}
+
+// isBarcodeFieldName reports whether f's key or label contains a barcode word, e.g.
+// "Barcode", "Product EAN" or "sku_code".
+func isBarcodeFieldName(f orcaField) bool {
+	for _, name := range []string{f.Key, f.Label} {
+		words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			if _, ok := barcodeNameWords[word]; ok {
+				return true
+			}
+			if strings.Contains(word, "barcode") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enumValue converts a cell of an enum column to its trimmed text.
+func enumValue(value any) any {
+	s, ok := stringifyValue(value).(string)
+	if !ok {
+		return value
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// enumValues returns the sorted distinct texts of an enum column.
+func enumValues(key string, rows []map[string]any) []string {
+	seen := make(map[string]struct{})
+	for _, row := range rows {
+		if s, ok := enumValue(row[key]).(string); ok {
+			seen[s] = struct{}{}
+		}
+	}
+	return sortedEnumValues(seen)
+}
+
+// mergeEnumValues returns the sorted union of two enum value lists.
+func mergeEnumValues(a, b []string) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for _, s := range a {
+		seen[s] = struct{}{}
+	}
+	for _, s := range b {
+		seen[s] = struct{}{}
+	}
+	return sortedEnumValues(seen)
+}
+
+func sortedEnumValues(seen map[string]struct{}) []string {
+	if len(seen) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(seen))
+	for s := range seen {
+		values = append(values, s)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// normalizeJSON decodes string cells holding JSON objects or arrays; anything else is
+// returned unchanged.
+func normalizeJSON(value any) any {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return value
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
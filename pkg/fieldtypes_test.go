@@ -0,0 +1,116 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestParseFieldKind(t *testing.T) {
+	cases := map[string]fieldKind{
+		"string": fieldKindString,
+		"Number": fieldKindNumber,
+		"bool":   fieldKindBoolean,
+		"date":   fieldKindTime,
+		"gps":    fieldKindGeo,
+		"enum":   fieldKindEnum,
+		" json ": fieldKindJSON,
+	}
+	for raw, want := range cases {
+		if got, err := parseFieldKind(raw); err != nil || got != want {
+			t.Errorf("parseFieldKind(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	if _, err := parseFieldKind("currency"); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
+
+func TestIsBarcodeFieldName(t *testing.T) {
+	for _, name := range []string{"Barcode", "Product EAN", "sku_code", "EAN13", "GTIN-14", "ItemBarcode"} {
+		if !isBarcodeFieldName(orcaField{Key: name}) {
+			t.Errorf("expected %q to be barcode-like", name)
+		}
+	}
+	for _, name := range []string{"Mean", "Quantity", "Location Code", "Supplier"} {
+		if isBarcodeFieldName(orcaField{Key: name}) {
+			t.Errorf("expected %q not to be barcode-like", name)
+		}
+	}
+}
+
+func TestBuildFieldDescriptorsTypeOverrides(t *testing.T) {
+	fields := []orcaField{
+		{Key: "Barcode"},
+		{Key: "Flag"},
+		{Key: "Status"},
+		{Key: "Meta"},
+		{Key: "Count"},
+	}
+	rows := []map[string]any{
+		{"Barcode": "00123", "Flag": "1", "Status": " Open", "Meta": `{"a":1}`, "Count": "007"},
+		{"Barcode": "04567", "Flag": "0", "Status": "Closed", "Meta": "n/a", "Count": "12"},
+	}
+
+	inst := &orcaInstance{keepBarcodes: true}
+	opts, err := inst.queryParseOptions(models.OrcaQuery{FieldTypes: map[string]string{
+		"flag":   "number",
+		"Status": "enum",
+		"meta":   "json",
+	}})
+	if err != nil {
+		t.Fatalf("queryParseOptions: %v", err)
+	}
+
+	_, descMap := buildFieldDescriptors(fields, rows, opts)
+	wantKinds := map[string]fieldKind{
+		"Barcode": fieldKindBarcode,
+		"Flag":    fieldKindNumber,
+		"Status":  fieldKindEnum,
+		"Meta":    fieldKindJSON,
+		"Count":   fieldKindNumber,
+	}
+	for key, want := range wantKinds {
+		if got := descMap[key].kind; got != want {
+			t.Errorf("%s: expected kind %v, got %v", key, want, got)
+		}
+	}
+	if got := descMap["Status"].enumValues; !reflect.DeepEqual(got, []string{"Closed", "Open"}) {
+		t.Fatalf("unexpected enum values %v", got)
+	}
+
+	normalized := normalizeRows(rows, descMap)
+	if normalized[0]["Barcode"] != "00123" {
+		t.Fatalf("expected barcode to keep its leading zeros, got %v", normalized[0]["Barcode"])
+	}
+	if normalized[0]["Status"] != "Open" {
+		t.Fatalf("expected trimmed enum text, got %v", normalized[0]["Status"])
+	}
+	if meta, ok := normalized[0]["Meta"].(map[string]any); !ok || meta["a"] != float64(1) {
+		t.Fatalf("expected decoded JSON object, got %#v", normalized[0]["Meta"])
+	}
+	if normalized[1]["Meta"] != "n/a" {
+		t.Fatalf("expected invalid JSON to be kept, got %v", normalized[1]["Meta"])
+	}
+
+	cfg := fieldConfig(descMap["Status"])
+	if cfg == nil || cfg.TypeConfig == nil || !reflect.DeepEqual(cfg.TypeConfig.Enum.Text, []string{"Closed", "Open"}) {
+		t.Fatalf("expected enum type config, got %+v", cfg)
+	}
+
+	inst.keepBarcodes = false
+	opts, _ = inst.queryParseOptions(models.OrcaQuery{})
+	_, descMap = buildFieldDescriptors(fields, rows, opts)
+	if descMap["Barcode"].kind != fieldKindNumber {
+		t.Fatalf("expected barcode protection to be optional, got %v", descMap["Barcode"].kind)
+	}
+}
+
+func TestQueryParseOptionsRejectsUnknownFieldType(t *testing.T) {
+	inst := &orcaInstance{}
+	_, err := inst.queryParseOptions(models.OrcaQuery{FieldTypes: map[string]string{"Qty": "money"}})
+	if statusFromError(err) != 400 {
+		t.Fatalf("expected request error, got %v", err)
+	}
+}
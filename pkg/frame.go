@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+// queryFormatFrame returns typed Grafana data frames instead of JSON rows, so the
+// frontend no longer rebuilds columns from the field list.
+const queryFormatFrame = "frame"
+
+// buildDataFrame turns rows into a frame with one nullable column per field, typed by
+// its GrafanaType and carrying its config. Values that do not fit a column's type
+// become null.
+func buildDataFrame(name string, rows []map[string]any, fields []models.Field, timeField string) *data.Frame {
+	frame := data.NewFrame(name)
+	for _, info := range fields {
+		field := frameField(info, rows)
+		field.Config = frameFieldConfig(info)
+		frame.Fields = append(frame.Fields, field)
+	}
+
+	var visualization data.VisType = data.VisTypeTable
+	if timeField != "" && len(rows) > 0 {
+		visualization = data.VisTypeGraph
+	}
+	frame.Meta = &data.FrameMeta{PreferredVisualization: visualization}
+	return frame
+}
+
+func frameField(info models.Field, rows []map[string]any) *data.Field {
+	switch info.GrafanaType {
+	case "number":
+		values := make([]*float64, len(rows))
+		for idx, row := range rows {
+			switch v := row[info.Key].(type) {
+			case float64, float32, int, int32, int64, uint, uint32, uint64:
+				if f := toFloat64(v); !math.IsNaN(f) && !math.IsInf(f, 0) {
+					values[idx] = &f
+				}
+			}
+		}
+		return data.NewField(info.Key, nil, values)
+	case "boolean":
+		values := make([]*bool, len(rows))
+		for idx, row := range rows {
+			if b, ok := row[info.Key].(bool); ok {
+				values[idx] = &b
+			}
+		}
+		return data.NewField(info.Key, nil, values)
+	case "time":
+		values := make([]*time.Time, len(rows))
+		for idx, row := range rows {
+			switch v := row[info.Key].(type) {
+			case time.Time:
+				values[idx] = &v
+			case string:
+				if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+					values[idx] = &t
+				}
+			}
+		}
+		return data.NewField(info.Key, nil, values)
+	case "enum":
+		// Enum columns hold indexes into the config's enum text.
+		var text []string
+		if info.Config != nil && info.Config.TypeConfig != nil && info.Config.TypeConfig.Enum != nil {
+			text = info.Config.TypeConfig.Enum.Text
+		}
+		index := make(map[string]data.EnumItemIndex, len(text))
+		for idx, s := range text {
+			index[s] = data.EnumItemIndex(idx)
+		}
+		values := make([]*data.EnumItemIndex, len(rows))
+		for idx, row := range rows {
+			if s, ok := enumValue(row[info.Key]).(string); ok {
+				if i, found := index[s]; found {
+					values[idx] = &i
+				}
+			}
+		}
+		return data.NewField(info.Key, nil, values)
+	case "other":
+		values := make([]*json.RawMessage, len(rows))
+		for idx, row := range rows {
+			val := row[info.Key]
+			if val == nil {
+				continue
+			}
+			if raw, err := json.Marshal(val); err == nil {
+				msg := json.RawMessage(raw)
+				values[idx] = &msg
+			}
+		}
+		return data.NewField(info.Key, nil, values)
+	default:
+		values := make([]*string, len(rows))
+		for idx, row := range rows {
+			if s, ok := stringifyValue(row[info.Key]).(string); ok {
+				values[idx] = &s
+			}
+		}
+		return data.NewField(info.Key, nil, values)
+	}
+}
+
+// frameFieldConfig copies the field's config, adding its label as the display name
+// the way the frontend does for JSON rows.
+func frameFieldConfig(info models.Field) *data.FieldConfig {
+	needsName := info.Label != "" && info.Label != info.Key
+	needsDecimals := info.GrafanaType == "number" && info.Decimals != nil && *info.Decimals > 0
+	if info.Config == nil && !needsName && !needsDecimals {
+		return nil
+	}
+
+	var cfg data.FieldConfig
+	if info.Config != nil {
+		cfg = *info.Config
+	}
+	if cfg.DisplayNameFromDS == "" && needsName {
+		cfg.DisplayNameFromDS = info.Label
+	}
+	if cfg.Decimals == nil && needsDecimals {
+		d := uint16(*info.Decimals)
+		cfg.Decimals = &d
+	}
+	return &cfg
+}
+
+// writeFrame answers a frame-format query with its rows as a single data frame.
+func writeFrame(w http.ResponseWriter, query models.OrcaQuery, rows []map[string]any, fields []models.Field, timeField string) {
+	name := query.SheetID
+	if name == "" {
+		name = query.RefID
+	}
+	frame := buildDataFrame(name, rows, fields, timeField)
+	frame.RefID = query.RefID
+
+	writeJSON(w, http.StatusOK, apiResponse{
+		"frames":    []*data.Frame{frame},
+		"refId":     query.RefID,
+		"sheetId":   query.SheetID,
+		"timeField": timeField,
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestBuildDataFrame(t *testing.T) {
+	when := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := []map[string]any{
+		{"When": when, "Qty": 2.5, "Done": true, "Status": "Open", "Meta": map[string]any{"a": 1.0}, "Name": "box"},
+		{"When": nil, "Qty": "n/a", "Done": nil, "Status": "Lost", "Name": 12.0},
+	}
+	decimals := 1
+	fields := []models.Field{
+		{Key: "When", GrafanaType: "time", IsTime: true},
+		{Key: "Qty", Label: "Quantity", GrafanaType: "number", Decimals: &decimals},
+		{Key: "Done", GrafanaType: "boolean"},
+		{Key: "Status", GrafanaType: "enum", Config: &data.FieldConfig{TypeConfig: &data.FieldTypeConfig{Enum: &data.EnumFieldConfig{Text: []string{"Closed", "Open"}}}}},
+		{Key: "Meta", GrafanaType: "other"},
+		{Key: "Name", GrafanaType: "string"},
+	}
+
+	frame := buildDataFrame("sheet", rows, fields, "When")
+	if frame.Rows() != 2 || len(frame.Fields) != 6 {
+		t.Fatalf("unexpected frame shape %d x %d", frame.Rows(), len(frame.Fields))
+	}
+	if frame.Meta.PreferredVisualization != data.VisTypeGraph {
+		t.Fatalf("unexpected visualization %q", frame.Meta.PreferredVisualization)
+	}
+
+	wantTypes := []data.FieldType{
+		data.FieldTypeNullableTime,
+		data.FieldTypeNullableFloat64,
+		data.FieldTypeNullableBool,
+		data.FieldTypeNullableEnum,
+		data.FieldTypeNullableJSON,
+		data.FieldTypeNullableString,
+	}
+	for idx, want := range wantTypes {
+		if got := frame.Fields[idx].Type(); got != want {
+			t.Fatalf("field %s: got type %s, want %s", frame.Fields[idx].Name, got, want)
+		}
+	}
+
+	if v, _ := frame.Fields[1].ConcreteAt(0); v != 2.5 {
+		t.Fatalf("unexpected number %v", v)
+	}
+	if _, ok := frame.Fields[1].ConcreteAt(1); ok {
+		t.Fatal("expected a non-numeric value to be null")
+	}
+	if v, _ := frame.Fields[3].ConcreteAt(0); v != data.EnumItemIndex(1) {
+		t.Fatalf("unexpected enum index %v", v)
+	}
+	if _, ok := frame.Fields[3].ConcreteAt(1); ok {
+		t.Fatal("expected an unknown enum value to be null")
+	}
+	if v, _ := frame.Fields[5].ConcreteAt(1); v != "12" {
+		t.Fatalf("unexpected string %v", v)
+	}
+
+	cfg := frame.Fields[1].Config
+	if cfg == nil || cfg.DisplayNameFromDS != "Quantity" || cfg.Decimals == nil || *cfg.Decimals != 1 {
+		t.Fatalf("unexpected number config %+v", cfg)
+	}
+	if frame.Fields[0].Config != nil {
+		t.Fatalf("expected no config for a plain field, got %+v", frame.Fields[0].Config)
+	}
+
+	raw, err := json.Marshal(frame)
+	if err != nil {
+		t.Fatalf("marshal frame: %v", err)
+	}
+	if !strings.Contains(string(raw), `"schema"`) || !strings.Contains(string(raw), `"Quantity"`) {
+		t.Fatalf("unexpected frame JSON %s", raw)
+	}
+}
+
+func TestFrameFieldConfigDoesNotShareConfig(t *testing.T) {
+	shared := &data.FieldConfig{Unit: "percent"}
+	cfg := frameFieldConfig(models.Field{Key: "rate", Label: "Rate", GrafanaType: "number", Config: shared})
+	if cfg.DisplayNameFromDS != "Rate" || cfg.Unit != "percent" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if shared.DisplayNameFromDS != "" {
+		t.Fatal("expected the shared config to be left untouched")
+	}
+}
+
+func TestQualityReportFrame(t *testing.T) {
+	rows := []map[string]any{{"Qty": 1.0}, {"Qty": "x"}, {"Qty": nil}}
+	report := qualityRows(rows, []fieldDescriptor{{meta: orcaField{Key: "Qty"}, kind: fieldKindNumber}})
+
+	frame := buildDataFrame("quality", report, qualityFields, "")
+	if len(frame.Fields) != len(qualityFields) {
+		t.Fatalf("expected one column per quality field, got %d", len(frame.Fields))
+	}
+	invalid, _ := frame.FieldByName("invalid")
+	if got, ok := invalid.ConcreteAt(0); !ok || got != 1.0 {
+		t.Fatalf("expected one invalid value as a number, got %v", got)
+	}
+}
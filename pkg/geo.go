@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// geoPoint is a parsed coordinate. alt holds an optional altitude in metres, and
+// accuracy the horizontal uncertainty in metres when the capture reported one.
+type geoPoint struct {
+	lat, lon                 float64
+	latDecimals, lonDecimals int
+
+	alt         float64
+	altDecimals int
+	hasAlt      bool
+
+	accuracy         float64
+	accuracyDecimals int
+	hasAccuracy      bool
+}
+
+// dmsBody matches the degrees, minutes and seconds of one coordinate, e.g. 51°30'26.5"
+// or -0° 7.65'.
+const dmsBody = `([+-]?\d+(?:\.\d+)?)\s*[°º]\s*(?:(\d+(?:\.\d+)?)\s*['′’]\s*)?(?:(\d+(?:\.\d+)?)\s*(?:"|″|”|'')\s*)?`
+
+// DMS coordinates carry their hemisphere either before (N51°30'26") or after
+// (51°30'26"N) each component; the two styles are matched separately so a trailing
+// letter is never mistaken for the next component's leading one.
+var (
+	dmsLeading  = regexp.MustCompile(`([NSEWnsew])\s*` + dmsBody)
+	dmsTrailing = regexp.MustCompile(dmsBody + `([NSEWnsew])?`)
+)
+
+// parseGeoPoint reads a coordinate from the forms GPS captures arrive in:
+//
+//	51.5072, -0.1275          comma, semicolon or pipe separated, optional third value
+//	51.5072 -0.1275 35        space separated
+//	51°30'26"N 0°7'39"W       degrees, minutes and seconds
+//	geo:51.5072,-0.1275;u=10  geo URI (RFC 5870)
+//	{"lat": 51.5, "lng": -0.1} JSON object, as a string or decoded
+func parseGeoPoint(val any) (geoPoint, bool) {
+	switch v := val.(type) {
+	case nil:
+		return geoPoint{}, false
+	case map[string]any:
+		return geoPointFromObject(v)
+	case string:
+		return parseGeoString(v)
+	case interface{ String() string }:
+		return parseGeoString(v.String())
+	default:
+		return geoPoint{}, false
+	}
+}
+
+func parseGeoString(raw string) (geoPoint, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return geoPoint{}, false
+	}
+
+	if strings.HasPrefix(raw, "{") {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+			return geoPoint{}, false
+		}
+		return geoPointFromObject(obj)
+	}
+
+	if len(raw) > 4 && strings.EqualFold(raw[:4], "geo:") {
+		return parseGeoURI(raw[4:])
+	}
+
+	if strings.ContainsAny(raw, "°º") {
+		return parseDMS(raw)
+	}
+
+	raw = strings.ReplaceAll(raw, ";", ",")
+	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '|' })
+	if len(parts) == 1 {
+		parts = strings.Fields(raw)
+	}
+	return geoPointFromTokens(parts)
+}
+
+// parseGeoURI reads the body of a geo URI: lat,lon[,alt] followed by parameters, of
+// which u= is the uncertainty in metres.
+func parseGeoURI(raw string) (geoPoint, bool) {
+	coords, params, _ := strings.Cut(raw, ";")
+	point, ok := geoPointFromTokens(strings.Split(coords, ","))
+	if !ok {
+		return point, false
+	}
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "u") {
+			continue
+		}
+		if u, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && u >= 0 {
+			point.accuracy = u
+			point.accuracyDecimals = decimalsInComponent(value)
+			point.hasAccuracy = true
+		}
+	}
+	return point, true
+}
+
+func geoPointFromTokens(parts []string) (geoPoint, bool) {
+	if len(parts) != 2 && len(parts) != 3 {
+		return geoPoint{}, false
+	}
+
+	values := make([]float64, len(parts))
+	for idx, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return geoPoint{}, false
+		}
+		values[idx] = v
+	}
+
+	point := geoPoint{
+		lat:         values[0],
+		lon:         values[1],
+		latDecimals: decimalsInComponent(parts[0]),
+		lonDecimals: decimalsInComponent(parts[1]),
+	}
+	if len(parts) == 3 {
+		point.alt = values[2]
+		point.altDecimals = decimalsInComponent(parts[2])
+		point.hasAlt = true
+	}
+	return point.valid()
+}
+
+func parseDMS(raw string) (geoPoint, bool) {
+	var matches [][]string
+	if first := strings.TrimLeft(raw, " "); first != "" && strings.ContainsRune("NSEWnsew", rune(first[0])) {
+		for _, m := range dmsLeading.FindAllStringSubmatch(raw, -1) {
+			matches = append(matches, append(m[1:], ""))
+		}
+	} else {
+		for _, m := range dmsTrailing.FindAllStringSubmatch(raw, -1) {
+			matches = append(matches, append([]string{""}, m[1:]...))
+		}
+	}
+	if len(matches) != 2 {
+		return geoPoint{}, false
+	}
+
+	var point geoPoint
+	var haveLat, haveLon bool
+	for idx, m := range matches {
+		// m is hemisphere, degrees, minutes, seconds, hemisphere.
+		hemisphere := strings.ToUpper(m[0] + m[4])
+
+		deg, _ := strconv.ParseFloat(m[1], 64)
+		negative := strings.HasPrefix(m[1], "-")
+		value := math.Abs(deg)
+		decimals := decimalsInComponent(m[1])
+		if m[2] != "" {
+			minutes, _ := strconv.ParseFloat(m[2], 64)
+			if minutes >= 60 {
+				return geoPoint{}, false
+			}
+			value += minutes / 60
+			decimals = 2 + decimalsInComponent(m[2])
+		}
+		if m[3] != "" {
+			seconds, _ := strconv.ParseFloat(m[3], 64)
+			if seconds >= 60 {
+				return geoPoint{}, false
+			}
+			value += seconds / 3600
+			decimals = 4 + decimalsInComponent(m[3])
+		}
+		if negative || hemisphere == "S" || hemisphere == "W" {
+			value = -value
+		}
+		value = roundTo(value, decimals)
+
+		isLat := hemisphere == "N" || hemisphere == "S" || (hemisphere == "" && idx == 0)
+		if isLat {
+			if haveLat {
+				return geoPoint{}, false
+			}
+			point.lat, point.latDecimals, haveLat = value, decimals, true
+		} else {
+			if haveLon {
+				return geoPoint{}, false
+			}
+			point.lon, point.lonDecimals, haveLon = value, decimals, true
+		}
+	}
+	return point.valid()
+}
+
+func geoPointFromObject(obj map[string]any) (geoPoint, bool) {
+	lat, latText, okLat := objectCoordinate(obj, "lat", "latitude")
+	lon, lonText, okLon := objectCoordinate(obj, "lng", "lon", "long", "longitude")
+	if !okLat || !okLon {
+		return geoPoint{}, false
+	}
+
+	point := geoPoint{
+		lat:         lat,
+		lon:         lon,
+		latDecimals: decimalsInComponent(latText),
+		lonDecimals: decimalsInComponent(lonText),
+	}
+	if alt, altText, ok := objectCoordinate(obj, "alt", "altitude"); ok {
+		point.alt = alt
+		point.altDecimals = decimalsInComponent(altText)
+		point.hasAlt = true
+	}
+	if accuracy, accuracyText, ok := objectCoordinate(obj, "accuracy"); ok {
+		point.accuracy = accuracy
+		point.accuracyDecimals = decimalsInComponent(accuracyText)
+		point.hasAccuracy = true
+	}
+	return point.valid()
+}
+
+// objectCoordinate returns the first of names present in obj as a number, along with
+// its text so decimals can be counted.
+func objectCoordinate(obj map[string]any, names ...string) (float64, string, bool) {
+	for _, name := range names {
+		for key, val := range obj {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			switch v := val.(type) {
+			case float64:
+				return v, strconv.FormatFloat(v, 'f', -1, 64), true
+			case json.Number:
+				f, err := v.Float64()
+				return f, v.String(), err == nil
+			case string:
+				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				return f, strings.TrimSpace(v), err == nil
+			}
+		}
+	}
+	return 0, "", false
+}
+
+func (p geoPoint) valid() (geoPoint, bool) {
+	if math.Abs(p.lat) > 90 || math.Abs(p.lon) > 180 {
+		return geoPoint{}, false
+	}
+	return p, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestParseGeoPointForms(t *testing.T) {
+	cases := []struct {
+		input          any
+		lat, lon       float64
+		latDec, lonDec int
+	}{
+		{"51.5072, -0.1275", 51.5072, -0.1275, 4, 4},
+		{"51.5072 -0.1275", 51.5072, -0.1275, 4, 4},
+		{`51°30'26"N 0°7'39"W`, 51.5072, -0.1275, 4, 4},
+		{`N51°30'26" W0°7'39"`, 51.5072, -0.1275, 4, 4},
+		{"33°51.5′S, 151°12.5′E", -33.858, 151.208, 3, 3},
+		{"geo:51.5072,-0.1275;u=35", 51.5072, -0.1275, 4, 4},
+		{`{"lat": 51.5072, "lng": -0.1275}`, 51.5072, -0.1275, 4, 4},
+		{map[string]any{"latitude": "51.50", "longitude": -0.1275}, 51.5, -0.1275, 2, 4},
+	}
+	for _, tc := range cases {
+		point, ok := parseGeoPoint(tc.input)
+		if !ok {
+			t.Errorf("parseGeoPoint(%v) failed", tc.input)
+			continue
+		}
+		if point.lat != tc.lat || point.lon != tc.lon || point.latDecimals != tc.latDec || point.lonDecimals != tc.lonDec {
+			t.Errorf("parseGeoPoint(%v) = %+v; want %v,%v (%d,%d decimals)", tc.input, point, tc.lat, tc.lon, tc.latDec, tc.lonDec)
+		}
+	}
+
+	for _, bad := range []any{"91°N 0°E", `51°30'N 52°0'N`, "1 2 3 4", "geo:", 42.0} {
+		if _, ok := parseGeoPoint(bad); ok {
+			t.Errorf("expected %v to be rejected", bad)
+		}
+	}
+}
+
+func TestGeoAltitudeColumn(t *testing.T) {
+	rows := []map[string]any{
+		{"GPS": "51.5072,-0.1275,35.5"},
+		{"GPS": `{"lat": 40.7128, "lng": -74.006, "accuracy": 12}`},
+		{"GPS": "geo:48.2010,16.3695,183;u=6.5"},
+	}
+	descMap := map[string]fieldDescriptor{"GPS": {meta: orcaField{Key: "GPS"}, kind: fieldKindGeo}}
+
+	extended, success := extendRowsWithGeo(rows, descMap)
+	if extended[0]["GPS_alt"] != 35.5 || extended[2]["GPS_alt"] != 183.0 {
+		t.Fatalf("expected altitude values, got %v and %v", extended[0]["GPS_alt"], extended[2]["GPS_alt"])
+	}
+	if _, ok := extended[1]["GPS_alt"]; ok {
+		t.Fatalf("expected accuracy to stay out of the altitude column, got %v", extended[1]["GPS_alt"])
+	}
+	if extended[1]["GPS_accuracy"] != 12.0 || extended[2]["GPS_accuracy"] != 6.5 {
+		t.Fatalf("expected accuracy values, got %v and %v", extended[1]["GPS_accuracy"], extended[2]["GPS_accuracy"])
+	}
+
+	list, mapping := extendFieldDescriptorsForGeo([]fieldDescriptor{descMap["GPS"]}, descMap, success)
+	if len(list) != 5 || mapping["GPS_alt"].kind != fieldKindNumber || mapping["GPS_alt"].decimals != 1 {
+		t.Fatalf("expected altitude column with 1 decimal, got %+v", list)
+	}
+	if accuracy := mapping["GPS_accuracy"]; accuracy.meta.Label != "GPS Accuracy" || accuracy.decimals != 1 {
+		t.Fatalf("expected accuracy column with 1 decimal, got %+v", accuracy)
+	}
+}
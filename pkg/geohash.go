@@ -0,0 +1,204 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+const (
+	queryModeGeohash = "geohash"
+
+	defaultGeohashPrecision = 6 // cells of about 1.2 km by 0.6 km
+	maxGeohashPrecision     = 12
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geohashAggregates are the aggregates a geohash query can compute over its value column.
+var geohashAggregates = []string{"sum", "avg", "min", "max"}
+
+// geohashCell accumulates the points and values that fall into one cell.
+type geohashCell struct {
+	hash     string
+	lat, lon float64
+	count    int
+
+	values   int
+	sum      float64
+	min, max float64
+}
+
+// geohashEncode returns the geohash of a coordinate at precision characters, with the
+// centre of its cell.
+func geohashEncode(lat, lon float64, precision int) (string, float64, float64) {
+	latLo, latHi := -90.0, 90.0
+	lonLo, lonHi := -180.0, 180.0
+
+	var sb strings.Builder
+	bit, ch, even := 0, 0, true
+	for sb.Len() < precision {
+		// Bits alternate between longitude and latitude, longitude first.
+		if even {
+			mid := (lonLo + lonHi) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				lonLo = mid
+			} else {
+				ch <<= 1
+				lonHi = mid
+			}
+		} else {
+			mid := (latLo + latHi) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latLo = mid
+			} else {
+				ch <<= 1
+				latHi = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String(), (latLo + latHi) / 2, (lonLo + lonHi) / 2
+}
+
+// geohashBins counts the rows whose geo column falls into each geohash cell, busiest
+// cell first, and aggregates spec.Value per cell. Rows without a coordinate are skipped.
+// It returns the cell rows and their field descriptions.
+func geohashBins(rows []map[string]any, descriptors []fieldDescriptor, columns []string, spec *models.QueryGeohash) ([]map[string]any, []models.Field, error) {
+	if spec == nil {
+		spec = &models.QueryGeohash{}
+	}
+	precision := spec.Precision
+	if precision == 0 {
+		precision = defaultGeohashPrecision
+	}
+	if precision < 1 || precision > maxGeohashPrecision {
+		return nil, nil, newRequestError("geohash precision must be between 1 and %d", maxGeohashPrecision)
+	}
+
+	geoKey, err := resolveGeoField(spec.Field, descriptors, columns, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var valueKey string
+	var aggregates []string
+	if name := normalizeFieldKey(spec.Value); name != "" {
+		key, ok := resolveFieldKey(name, descriptors, rows)
+		if !ok {
+			return nil, nil, newRequestError("geohash value field %q not found", spec.Value)
+		}
+		kind := detectKindFromRows(key, rows, fieldKindString)
+		for _, desc := range descriptors {
+			if desc.meta.Key == key {
+				kind = desc.kind
+			}
+		}
+		if kind != fieldKindNumber {
+			return nil, nil, newRequestError("geohash value field %q must be a number, not %s", key, kindName(kind))
+		}
+		valueKey = key
+
+		aggregates = []string{"avg"}
+		if len(spec.Aggregates) > 0 {
+			aggregates = make([]string, 0, len(spec.Aggregates))
+			for _, agg := range spec.Aggregates {
+				agg = strings.ToLower(strings.TrimSpace(agg))
+				if !containsString(geohashAggregates, agg) {
+					return nil, nil, newRequestError("unknown geohash aggregate %q (want sum, avg, min or max)", agg)
+				}
+				aggregates = append(aggregates, agg)
+			}
+		}
+	} else if len(spec.Aggregates) > 0 {
+		return nil, nil, newRequestError("geohash aggregates need a value field")
+	}
+
+	cells := make(map[string]*geohashCell)
+	for _, row := range rows {
+		point, ok := parseGeoPoint(row[geoKey])
+		if !ok {
+			continue
+		}
+		hash, lat, lon := geohashEncode(point.lat, point.lon, precision)
+		cell := cells[hash]
+		if cell == nil {
+			cell = &geohashCell{hash: hash, lat: roundTo(lat, 9), lon: roundTo(lon, 9)}
+			cells[hash] = cell
+		}
+		cell.count++
+
+		if valueKey == "" {
+			continue
+		}
+		v, ok := row[valueKey].(float64)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if cell.values == 0 || v < cell.min {
+			cell.min = v
+		}
+		if cell.values == 0 || v > cell.max {
+			cell.max = v
+		}
+		cell.values++
+		cell.sum += v
+	}
+
+	ordered := make([]*geohashCell, 0, len(cells))
+	for _, cell := range cells {
+		ordered = append(ordered, cell)
+	}
+	sort.Slice(ordered, func(a, b int) bool {
+		if ordered[a].count != ordered[b].count {
+			return ordered[a].count > ordered[b].count
+		}
+		return ordered[a].hash < ordered[b].hash
+	})
+
+	out := make([]map[string]any, 0, len(ordered))
+	for _, cell := range ordered {
+		row := map[string]any{
+			"geohash":   cell.hash,
+			"latitude":  cell.lat,
+			"longitude": cell.lon,
+			"count":     float64(cell.count),
+		}
+		for _, agg := range aggregates {
+			var val any
+			if cell.values > 0 {
+				switch agg {
+				case "sum":
+					val = cell.sum
+				case "avg":
+					val = cell.sum / float64(cell.values)
+				case "min":
+					val = cell.min
+				case "max":
+					val = cell.max
+				}
+			}
+			row[valueKey+"_"+agg] = val
+		}
+		out = append(out, row)
+	}
+
+	fields := []models.Field{
+		{Key: "geohash", Label: "Geohash", GrafanaType: "string"},
+		{Key: "latitude", Label: "Latitude", GrafanaType: "number"},
+		{Key: "longitude", Label: "Longitude", GrafanaType: "number"},
+		{Key: "count", Label: "Count", GrafanaType: "number"},
+	}
+	for _, agg := range aggregates {
+		fields = append(fields, models.Field{Key: valueKey + "_" + agg, Label: valueKey + " (" + agg + ")", GrafanaType: "number"})
+	}
+	return out, fields, nil
+}
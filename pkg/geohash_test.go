@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestGeohashEncode(t *testing.T) {
+	hash, lat, lon := geohashEncode(57.64911, 10.40744, 11)
+	if hash != "u4pruydqqvj" {
+		t.Fatalf("unexpected geohash %q", hash)
+	}
+	if lat < 57.6491 || lat > 57.6492 || lon < 10.4074 || lon > 10.4075 {
+		t.Fatalf("unexpected cell centre %v, %v", lat, lon)
+	}
+}
+
+func TestGeohashBins(t *testing.T) {
+	rows := []map[string]any{
+		{"Location": "51.5072, -0.1275", "Qty": 2.0},
+		{"Location": "51.5073, -0.1276", "Qty": 4.0},
+		{"Location": "52.2053, 0.1218", "Qty": nil},
+		{"Location": nil, "Qty": 8.0},
+	}
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "Location"}, kind: fieldKindGeo},
+		{meta: orcaField{Key: "Qty"}, kind: fieldKindNumber},
+	}
+
+	cells, fields, err := geohashBins(rows, descriptors, nil, &models.QueryGeohash{Precision: 5, Value: "qty", Aggregates: []string{"sum", "AVG"}})
+	if err != nil {
+		t.Fatalf("geohashBins: %v", err)
+	}
+	if len(cells) != 2 || cells[0]["geohash"] != "gcpvj" || cells[0]["count"] != 2.0 {
+		t.Fatalf("unexpected cells %v", cells)
+	}
+	if cells[0]["Qty_sum"] != 6.0 || cells[0]["Qty_avg"] != 3.0 || cells[1]["Qty_sum"] != nil {
+		t.Fatalf("unexpected aggregates %v", cells)
+	}
+	if len(fields) != 6 || fields[5].Key != "Qty_avg" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
+
+func TestGeohashBinsInvalid(t *testing.T) {
+	rows := []map[string]any{{"Location": "51.5, -0.1", "Name": "depot"}}
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "Location"}, kind: fieldKindGeo},
+		{meta: orcaField{Key: "Name"}, kind: fieldKindString},
+	}
+	specs := []*models.QueryGeohash{
+		{Precision: 13},
+		{Value: "Name"},
+		{Value: "Missing"},
+		{Aggregates: []string{"sum"}},
+	}
+	for _, spec := range specs {
+		if _, _, err := geohashBins(rows, descriptors, nil, spec); statusFromError(err) != 400 {
+			t.Fatalf("expected request error for %+v, got %v", spec, err)
+		}
+	}
+}
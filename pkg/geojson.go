@@ -0,0 +1,100 @@
+package main
+
+const (
+	queryFormatRows    = "rows"
+	queryFormatGeoJSON = "geojson"
+)
+
+// GeoJSON (RFC 7946) types for the geojson query format.
+type geoJSONFeature struct {
+	Type       string           `json:"type"`
+	ID         any              `json:"id,omitempty"`
+	Geometry   *geoJSONGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// geoJSONLatField and geoJSONLonField are the columns the frontend builds from each
+// feature's geometry so geomap panels locate points automatically.
+var (
+	geoJSONLatField = fieldDescriptor{meta: orcaField{Key: "latitude", Label: "Latitude", Type: "number"}, kind: fieldKindNumber}
+	geoJSONLonField = fieldDescriptor{meta: orcaField{Key: "longitude", Label: "Longitude", Type: "number"}, kind: fieldKindNumber}
+)
+
+// resolveGeoField returns the column a geo output uses: the requested one, or else the
+// first geo column, checking descriptors and then columns in sheet order.
+func resolveGeoField(requested string, descriptors []fieldDescriptor, columns []string, rows []map[string]any) (string, error) {
+	if requested = normalizeFieldKey(requested); requested != "" {
+		key, ok := resolveFieldKey(requested, descriptors, rows)
+		if !ok {
+			return "", newRequestError("geo field %q not found", requested)
+		}
+		return key, nil
+	}
+	for _, desc := range descriptors {
+		if desc.kind == fieldKindGeo {
+			return desc.meta.Key, nil
+		}
+	}
+	for _, key := range columns {
+		if detectKindFromRows(key, rows, fieldKindString) == fieldKindGeo {
+			return key, nil
+		}
+	}
+	return "", newRequestError("no geo column found; set geoField")
+}
+
+// buildFeatureCollection turns each row into a Point feature located by geoKey, with
+// the row's other columns as properties. The geo column and the _lat/_lon/_alt columns
+// split from it are left out of the properties; rows without a coordinate get a null
+// geometry. It returns the features and the descriptors of their properties, which
+// follow columns when there are no descriptors.
+func buildFeatureCollection(rows []map[string]any, descriptors []fieldDescriptor, columns []string, geoKey string) ([]geoJSONFeature, []fieldDescriptor) {
+	skip := map[string]struct{}{
+		geoKey:          {},
+		geoKey + "_lat": {},
+		geoKey + "_lon": {},
+		geoKey + "_alt": {},
+	}
+
+	props := make([]fieldDescriptor, 0, len(descriptors))
+	for _, desc := range descriptors {
+		if _, ok := skip[desc.meta.Key]; !ok {
+			props = append(props, desc)
+		}
+	}
+	if len(descriptors) == 0 {
+		for _, f := range fieldsFromColumns(columns) {
+			if _, ok := skip[f.Key]; !ok {
+				props = append(props, fieldDescriptor{meta: f, kind: detectKindFromRows(f.Key, rows, fieldKindString)})
+			}
+		}
+	}
+
+	features := make([]geoJSONFeature, 0, len(rows))
+	for _, row := range rows {
+		feature := geoJSONFeature{
+			Type:       "Feature",
+			ID:         row["_id"],
+			Properties: make(map[string]any, len(props)),
+		}
+		if point, ok := parseGeoPoint(row[geoKey]); ok {
+			coords := []float64{point.lon, point.lat}
+			if point.hasAlt {
+				coords = append(coords, point.alt)
+			}
+			feature.Geometry = &geoJSONGeometry{Type: "Point", Coordinates: coords}
+		}
+		for _, desc := range props {
+			if val, ok := row[desc.meta.Key]; ok {
+				feature.Properties[desc.meta.Key] = val
+			}
+		}
+		features = append(features, feature)
+	}
+	return features, props
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBuildFeatureCollection(t *testing.T) {
+	rows := []map[string]any{
+		{"_id": "a", "GPS": "51.5072,-0.1275,35", "GPS_lat": 51.5072, "GPS_lon": -0.1275, "GPS_alt": 35.0, "Name": "London"},
+		{"_id": "b", "GPS": "", "Name": "Nowhere"},
+	}
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "Name"}, kind: fieldKindString},
+		{meta: orcaField{Key: "GPS"}, kind: fieldKindGeo},
+		{meta: orcaField{Key: "GPS_lat"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "GPS_lon"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "GPS_alt"}, kind: fieldKindNumber},
+	}
+
+	key, err := resolveGeoField("", descriptors, nil, rows)
+	if err != nil || key != "GPS" {
+		t.Fatalf("expected first geo column, got %q (%v)", key, err)
+	}
+	if _, err := resolveGeoField("missing", descriptors, nil, rows); err == nil {
+		t.Fatal("expected unknown geo field error")
+	}
+
+	features, props := buildFeatureCollection(rows, descriptors, nil, key)
+	if len(props) != 1 || props[0].meta.Key != "Name" {
+		t.Fatalf("expected only Name as a property, got %+v", props)
+	}
+
+	encoded, err := json.Marshal(features)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `[{"type":"Feature","id":"a","geometry":{"type":"Point","coordinates":[-0.1275,51.5072,35]},"properties":{"Name":"London"}},` +
+		`{"type":"Feature","id":"b","geometry":null,"properties":{"Name":"Nowhere"}}]`
+	if string(encoded) != want {
+		t.Fatalf("unexpected features\n got %s\nwant %s", encoded, want)
+	}
+}
+
+func TestFeatureCollectionKeepsAccuracyOutOfCoordinates(t *testing.T) {
+	rows := []map[string]any{
+		{"GPS": `{"lat": 40.7128, "lng": -74.006, "accuracy": 12}`, "GPS_lat": 40.7128, "GPS_lon": -74.006, "GPS_accuracy": 12.0},
+	}
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "GPS"}, kind: fieldKindGeo},
+		{meta: orcaField{Key: "GPS_lat"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "GPS_lon"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "GPS_accuracy"}, kind: fieldKindNumber},
+	}
+
+	features, _ := buildFeatureCollection(rows, descriptors, nil, "GPS")
+	if coords := features[0].Geometry.Coordinates; len(coords) != 2 {
+		t.Fatalf("expected a 2D point, got %v", coords)
+	}
+	if features[0].Properties["GPS_accuracy"] != 12.0 {
+		t.Fatalf("expected accuracy as a property, got %v", features[0].Properties)
+	}
+}
+
+func TestFeatureCollectionFollowsColumnsWithoutDescriptors(t *testing.T) {
+	rows := []map[string]any{{"Zone": "B", "GPS": "51.5,-0.12", "Name": "London", "Area": 3.0}}
+	columns := []string{"Zone", "GPS", "Name", "Area"}
+
+	key, err := resolveGeoField("", nil, columns, rows)
+	if err != nil || key != "GPS" {
+		t.Fatalf("expected GPS as the geo column, got %q (%v)", key, err)
+	}
+	_, props := buildFeatureCollection(rows, nil, columns, key)
+	got := make([]string, 0, len(props))
+	for _, desc := range props {
+		got = append(got, desc.meta.Key)
+	}
+	if want := []string{"Zone", "Name", "Area"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected properties in sheet order %v, got %v", want, got)
+	}
+}
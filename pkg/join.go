@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+const defaultJoinPrefix = "right_"
+
+// maxJoinRows caps the rows read from a join's secondary sheet, which is paged through
+// in full so that no matches are dropped.
+const maxJoinRows = 50000
+
+type joinType string
+
+const (
+	joinTypeInner joinType = "inner"
+	joinTypeLeft  joinType = "left"
+)
+
+func parseJoinType(v string) (joinType, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "inner":
+		return joinTypeInner, true
+	case "left":
+		return joinTypeLeft, true
+	default:
+		return "", false
+	}
+}
+
+// joinSheet loads the secondary sheet described by spec and joins its rows onto primary.
+func (i *orcaInstance) joinSheet(ctx context.Context, primary sheetData, spec models.QueryJoin, opts parseOptions) (sheetData, error) {
+	sheetID := strings.TrimSpace(spec.SheetID)
+	if sheetID == "" && strings.TrimSpace(spec.SheetName) != "" {
+		sheet, err := i.resolveSheetName(ctx, spec.SheetName)
+		if err != nil {
+			return sheetData{}, err
+		}
+		sheetID = sheet.ID
+	}
+	if sheetID == "" {
+		return sheetData{}, newRequestError("join sheetId or sheetName is required")
+	}
+
+	kind, ok := parseJoinType(spec.Type)
+	if !ok {
+		return sheetData{}, newRequestError("unsupported join type %q (expected inner or left)", spec.Type)
+	}
+
+	leftInput := normalizeFieldKey(spec.LeftKey)
+	if leftInput == "" {
+		return sheetData{}, newRequestError("join leftKey is required")
+	}
+	rightInput := normalizeFieldKey(spec.RightKey)
+	if rightInput == "" {
+		rightInput = leftInput
+	}
+
+	rows, columns, err := i.listAllRows(ctx, sheetID, maxJoinRows)
+	if err != nil {
+		return sheetData{}, err
+	}
+	secondary := i.buildSheet(ctx, sheetID, rows, columns, opts)
+
+	leftKey, ok := resolveFieldKey(leftInput, primary.descList, primary.rows)
+	if !ok {
+		return sheetData{}, newRequestError("join key %q not found in sheet %s", leftInput, primary.sheetID)
+	}
+	rightKey, ok := resolveFieldKey(rightInput, secondary.descList, secondary.rows)
+	if !ok {
+		return sheetData{}, newRequestError("join key %q not found in sheet %s", rightInput, sheetID)
+	}
+
+	prefix := spec.Prefix
+	if prefix == "" {
+		prefix = defaultJoinPrefix
+	}
+
+	return joinSheetData(primary, secondary, leftKey, rightKey, kind, prefix), nil
+}
+
+// listAllRows pages through every row of sheetID. Sheets with more than limit rows are a
+// request error rather than being cut short.
+func (i *orcaInstance) listAllRows(ctx context.Context, sheetID string, limit int) ([]map[string]any, []string, error) {
+	pageSize := sanitizeLimit(0)
+	var rows []map[string]any
+	var columns []string
+	seen := make(map[string]struct{})
+	for {
+		page, pageColumns, err := i.listRows(ctx, sheetID, pageSize, len(rows))
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, page...)
+		for _, key := range pageColumns {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				columns = append(columns, key)
+			}
+		}
+		if len(rows) > limit {
+			return nil, nil, newRequestError("join sheet %s has more than %d rows", sheetID, limit)
+		}
+		if len(page) < pageSize {
+			return rows, columns, nil
+		}
+	}
+}
+
+// joinSheetData joins right onto left where the normalized values of leftKey and rightKey match.
+// Columns of right that collide with columns of left are renamed with prefix; the right join
+// key is dropped because it duplicates the left one on every matched row.
+func joinSheetData(left, right sheetData, leftKey, rightKey string, kind joinType, prefix string) sheetData {
+	taken := make(map[string]struct{}, len(left.descMap))
+	for key := range left.descMap {
+		taken[key] = struct{}{}
+	}
+	for _, row := range left.rows {
+		for key := range row {
+			taken[key] = struct{}{}
+		}
+	}
+
+	renamed := make(map[string]string)
+	rename := func(key string) string {
+		if newKey, ok := renamed[key]; ok {
+			return newKey
+		}
+		newKey := key
+		for {
+			if _, exists := taken[newKey]; !exists {
+				break
+			}
+			newKey = prefix + newKey
+		}
+		taken[newKey] = struct{}{}
+		renamed[key] = newKey
+		return newKey
+	}
+
+	descList := make([]fieldDescriptor, 0, len(left.descList)+len(right.descList))
+	descList = append(descList, left.descList...)
+	for _, desc := range right.descList {
+		if desc.meta.Key == rightKey {
+			continue
+		}
+		newKey := rename(desc.meta.Key)
+		if newKey != desc.meta.Key {
+			desc.meta.Label = prefix + labelOrKey(desc.meta)
+			desc.meta.Key = newKey
+		}
+		descList = append(descList, desc)
+	}
+
+	descMap := make(map[string]fieldDescriptor, len(descList))
+	for _, desc := range descList {
+		descMap[desc.meta.Key] = desc
+	}
+
+	index := make(map[string][]map[string]any, len(right.rows))
+	for _, row := range right.rows {
+		if k, ok := joinValueKey(row[rightKey]); ok {
+			index[k] = append(index[k], row)
+		}
+	}
+
+	joined := make([]map[string]any, 0, len(left.rows))
+	for _, row := range left.rows {
+		var matches []map[string]any
+		if k, ok := joinValueKey(row[leftKey]); ok {
+			matches = index[k]
+		}
+
+		if len(matches) == 0 {
+			if kind == joinTypeLeft {
+				joined = append(joined, row)
+			}
+			continue
+		}
+
+		for _, match := range matches {
+			out := make(map[string]any, len(row)+len(match))
+			for key, val := range row {
+				out[key] = val
+			}
+			for key, val := range match {
+				if key == rightKey {
+					continue
+				}
+				out[rename(key)] = val
+			}
+			joined = append(joined, out)
+		}
+	}
+
+	return sheetData{
+		sheetID:  left.sheetID,
+		rows:     joined,
+		descList: descList,
+		descMap:  descMap,
+		columns:  appendDescriptorColumns(left.columns, descList),
+	}
+}
+
+// joinValueKey reduces a normalized cell value to a comparable join key. Text keys are
+// only trimmed and case folded, so "LOC-01" matches " loc-01" but not "loc 01". Number
+// keys never equal text keys, except that a string spelling a number exactly as it
+// formats (such as "12" or "-5") matches that number; "1.5", "00123" and "1e3" stay text.
+func joinValueKey(val any) (string, bool) {
+	switch v := val.(type) {
+	case nil:
+		return "", false
+	case bool:
+		return "b:" + strconv.FormatBool(v), true
+	case time.Time:
+		return "t:" + v.UTC().Format(time.RFC3339Nano), true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return numberJoinKey(f), true
+		}
+		return joinValueKey(v.String())
+	case float64, float32, int, int32, int64, uint, uint32, uint64:
+		return numberJoinKey(toFloat64(v)), true
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return "", false
+		}
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == trimmed {
+			return numberJoinKey(f), true
+		}
+		return "s:" + strings.ToLower(trimmed), true
+	default:
+		return "", false
+	}
+}
+
+func numberJoinKey(f float64) string {
+	return "n:" + strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJoinSheetData(t *testing.T) {
+	left := sheetData{
+		sheetID: "inventory",
+		descList: []fieldDescriptor{
+			{meta: orcaField{Key: "Location Code"}, kind: fieldKindNumber},
+			{meta: orcaField{Key: "Name"}, kind: fieldKindString},
+		},
+		rows: []map[string]any{
+			{"Location Code": 12.0, "Name": "Widget"},
+			{"Location Code": 99.0, "Name": "Gadget"},
+		},
+	}
+	right := sheetData{
+		sheetID: "locations",
+		descList: []fieldDescriptor{
+			{meta: orcaField{Key: "Location Code"}, kind: fieldKindString},
+			{meta: orcaField{Key: "Name", Label: "Name"}, kind: fieldKindString},
+			{meta: orcaField{Key: "City"}, kind: fieldKindString},
+		},
+		rows: []map[string]any{
+			{"Location Code": "12", "Name": "Depot", "City": "Leeds"},
+		},
+	}
+
+	inner := joinSheetData(left, right, "Location Code", "Location Code", joinTypeInner, "loc_")
+	if len(inner.rows) != 1 {
+		t.Fatalf("expected 1 inner row, got %d", len(inner.rows))
+	}
+	row := inner.rows[0]
+	if row["Name"] != "Widget" || row["loc_Name"] != "Depot" || row["City"] != "Leeds" {
+		t.Fatalf("unexpected joined row: %v", row)
+	}
+	if _, ok := inner.descMap["loc_Name"]; !ok {
+		t.Fatalf("expected renamed descriptor for colliding column, got %v", inner.descMap)
+	}
+	if inner.descMap["loc_Name"].meta.Label != "loc_Name" {
+		t.Fatalf("expected prefixed label, got %q", inner.descMap["loc_Name"].meta.Label)
+	}
+	if len(inner.descList) != 4 {
+		t.Fatalf("expected right join key to be dropped, got %d descriptors", len(inner.descList))
+	}
+
+	leftJoin := joinSheetData(left, right, "Location Code", "Location Code", joinTypeLeft, "loc_")
+	if len(leftJoin.rows) != 2 {
+		t.Fatalf("expected 2 left-join rows, got %d", len(leftJoin.rows))
+	}
+	if _, ok := leftJoin.rows[1]["City"]; ok {
+		t.Fatalf("expected unmatched row without secondary columns, got %v", leftJoin.rows[1])
+	}
+}
+
+func TestJoinValueKey(t *testing.T) {
+	tests := []struct {
+		a, b any
+	}{
+		{12.0, "12"},
+		{"LOC-01", " loc-01 "},
+		{-5, "-5"},
+		{true, true},
+	}
+
+	for _, tc := range tests {
+		ka, okA := joinValueKey(tc.a)
+		kb, okB := joinValueKey(tc.b)
+		if !okA || !okB || ka != kb {
+			t.Fatalf("expected %v and %v to share a join key, got %q/%q", tc.a, tc.b, ka, kb)
+		}
+	}
+
+	if _, ok := joinValueKey(nil); ok {
+		t.Fatal("expected nil to have no join key")
+	}
+
+	distinct := []struct {
+		a, b any
+	}{
+		{"00123", "123"},
+		{15, "1.5"},
+		{5, "-5"},
+		{"LOC-01", "loc 01"},
+		{"1", true},
+	}
+	for _, tc := range distinct {
+		ka, _ := joinValueKey(tc.a)
+		kb, _ := joinValueKey(tc.b)
+		if ka == kb {
+			t.Fatalf("expected %v and %v to stay distinct, both keyed %q", tc.a, tc.b, ka)
+		}
+	}
+}
+
+func TestListAllRows(t *testing.T) {
+	total := 5003
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		var rows []string
+		for n := skip; n < min(skip+limit, total); n++ {
+			rows = append(rows, fmt.Sprintf(`{"id":%d}`, n))
+		}
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(rows, ","))
+	}))
+	defer srv.Close()
+	inst := &orcaInstance{baseURL: srv.URL, apiKey: "secret", httpClient: srv.Client()}
+
+	rows, columns, err := inst.listAllRows(context.Background(), "s", maxJoinRows)
+	if err != nil || len(rows) != total || rows[total-1]["id"] != float64(total-1) {
+		t.Fatalf("expected all %d rows, got %d (%v)", total, len(rows), err)
+	}
+	if len(columns) != 1 || columns[0] != "id" {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+
+	if _, _, err := inst.listAllRows(context.Background(), "s", 5001); statusFromError(err) != 400 {
+		t.Fatalf("expected a sheet over the limit to be a request error, got %v", err)
+	}
+}
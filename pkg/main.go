@@ -14,6 +14,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	_ "time/tzdata" // zone-less Orca values are interpreted in IANA zones that may be missing on the host
 	"unicode"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -27,6 +28,7 @@ import (
 const (
 	defaultBaseURL = "https://api.orcascan.com/v1"
 	fieldCacheTTL  = 5 * time.Minute
+	sheetCacheTTL  = time.Minute
 )
 
 type apiResponse map[string]any
@@ -38,9 +40,20 @@ type orcaDatasource struct {
 type orcaInstance struct {
 	baseURL      string
 	apiKey       string
-	httpClient   *http.Client
-	fieldCache   map[string]fieldCacheEntry
-	fieldCacheMu sync.RWMutex
+	timezone     string
+	dateOrder    string
+	decimalSep   string
+	keepBarcodes bool
+	// systemColumns is the datasource default for returning Orca's row metadata.
+	systemColumns bool
+	sampleSize    int
+	threshold     float64
+	httpClient    *http.Client
+	fieldCache    map[string]fieldCacheEntry
+	fieldCacheMu  sync.RWMutex
+	sheetCache    sheetCacheEntry
+	sheetCacheMu  sync.RWMutex
+	mediaCache    *mediaCache
 }
 
 type orcaSheet struct {
@@ -57,6 +70,8 @@ type orcaField struct {
 
 type orcaRowsResponse struct {
 	Data []map[string]any `json:"data"`
+	// Columns lists the row keys in the order the API returned them.
+	Columns []string `json:"-"`
 }
 
 type resourceQueryPayload struct {
@@ -64,10 +79,25 @@ type resourceQueryPayload struct {
 }
 
 type fieldCacheEntry struct {
-	fields    []orcaField
+	fields     []orcaField
+	fetchedAt  time.Time
+	detections map[string]kindDetection // keyed by detectionCacheKey
+}
+
+type sheetCacheEntry struct {
+	sheets    []orcaSheet
 	fetchedAt time.Time
 }
 
+type sheetData struct {
+	sheetID  string
+	rows     []map[string]any
+	descList []fieldDescriptor
+	descMap  map[string]fieldDescriptor
+	// columns lists the row keys in sheet order, for output paths without descriptors.
+	columns []string
+}
+
 type fieldKind int
 
 const (
@@ -76,6 +106,9 @@ const (
 	fieldKindBoolean
 	fieldKindTime
 	fieldKindGeo
+	fieldKindEnum
+	fieldKindJSON
+	fieldKindBarcode
 )
 
 func (k fieldKind) grafanaType() string {
@@ -86,8 +119,12 @@ func (k fieldKind) grafanaType() string {
 		return "boolean"
 	case fieldKindTime:
 		return "time"
-	case fieldKindGeo:
+	case fieldKindGeo, fieldKindBarcode:
 		return "string"
+	case fieldKindEnum:
+		return "enum"
+	case fieldKindJSON:
+		return "other"
 	default:
 		return "string"
 	}
@@ -98,11 +135,25 @@ type fieldDescriptor struct {
 	kind        fieldKind
 	decimals    int
 	hasDecimals bool
+	opts        parseOptions
+	unit        string
+	enumValues  []string // sorted distinct values of an enum column
+	confidence  float64  // share of sampled values that fit kind; 0 when not detected
+	offenders   int      // values that did not parse as kind and were nulled
+	system      bool     // an Orca row metadata key rather than a sheet field
+	link        bool     // values are web addresses, shown as links
+
+	dateAmbiguous bool
 }
 
 type geoColumnInfo struct {
 	latDecimals int
 	lonDecimals int
+	altDecimals int
+	hasAlt      bool // some values carried an altitude
+
+	accuracyDecimals int
+	hasAccuracy      bool // some values carried a horizontal accuracy
 }
 
 func newDatasource() *orcaDatasource {
@@ -127,13 +178,38 @@ func newDatasourceInstance(_ context.Context, settings backend.DataSourceInstanc
 
 	apiKey := strings.TrimSpace(settings.DecryptedSecureJSONData["apiKey"])
 
+	keepBarcodes := true
+	if cfg.KeepBarcodesAsText != nil {
+		keepBarcodes = *cfg.KeepBarcodesAsText
+	}
+
+	sampleSize := cfg.DetectionSampleSize
+	if sampleSize == 0 {
+		sampleSize = defaultDetectionSampleSize
+	}
+	threshold := cfg.DetectionThreshold
+	if threshold > 1 && threshold <= 100 {
+		threshold /= 100
+	}
+	if threshold <= 0 || threshold > 1 {
+		threshold = defaultDetectionThreshold
+	}
+
 	return &orcaInstance{
-		baseURL: baseURL,
-		apiKey:  apiKey,
+		baseURL:       baseURL,
+		apiKey:        apiKey,
+		timezone:      strings.TrimSpace(cfg.Timezone),
+		dateOrder:     strings.TrimSpace(cfg.DateOrder),
+		decimalSep:    strings.TrimSpace(cfg.DecimalSeparator),
+		keepBarcodes:  keepBarcodes,
+		systemColumns: cfg.IncludeSystemColumns,
+		sampleSize:    sampleSize,
+		threshold:     threshold,
 		httpClient: &http.Client{
 			Timeout: 15 * time.Second,
 		},
 		fieldCache: make(map[string]fieldCacheEntry),
+		mediaCache: newMediaCache(),
 	}, nil
 }
 
@@ -179,6 +255,8 @@ func (d *orcaDatasource) resourcesHandler() http.Handler {
 	mux.HandleFunc("/sheets", d.handleSheets)
 	mux.HandleFunc("/fields", d.handleFields)
 	mux.HandleFunc("/query", d.handleQuery)
+	mux.HandleFunc("/quality", d.handleQuality)
+	mux.HandleFunc("/media", d.handleMedia)
 	return mux
 }
 
@@ -268,8 +346,8 @@ func (d *orcaDatasource) handleFields(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	descList, _ := buildFieldDescriptors(fieldsMeta, nil)
-	fieldInfos := buildFieldInfos(descList, "")
+	descList, _ := buildFieldDescriptors(fieldsMeta, nil, parseOptions{})
+	fieldInfos := append(buildFieldInfos(descList, ""), systemTimeFieldInfos(fieldsMeta)...)
 
 	writeJSON(w, http.StatusOK, apiResponse{"fields": fieldInfos})
 }
@@ -297,7 +375,17 @@ func (d *orcaDatasource) handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := payload.Query
-	if query.SheetID == "" {
+	if query.SheetID == "" && strings.TrimSpace(query.SheetName) != "" {
+		sheet, err := inst.resolveSheetName(ctx, query.SheetName)
+		if err != nil {
+			backend.Logger.Warn("Query sheet name not resolved", "sheetName", query.SheetName, "err", err)
+			writeError(w, statusFromError(err), err)
+			return
+		}
+		query.SheetID = sheet.ID
+	}
+
+	if query.SheetID == "" && !isUnionQuery(query) {
 		backend.Logger.Debug("Query missing sheetId; returning empty result", "refId", query.RefID)
 		writeJSON(w, http.StatusOK, apiResponse{
 			"rows":      []map[string]any{},
@@ -312,26 +400,101 @@ func (d *orcaDatasource) handleQuery(w http.ResponseWriter, r *http.Request) {
 	limit := sanitizeLimit(query.Limit)
 	skip := sanitizeSkip(query.Skip)
 
+	opts, err := inst.queryParseOptions(query)
+	if err != nil {
+		backend.Logger.Warn("Query parse options invalid", "refId", query.RefID, "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(query.Format))
+	switch format {
+	case "", queryFormatRows, queryFormatGeoJSON, queryFormatFrame:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown query format %q", query.Format))
+		return
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(query.Mode))
+	if format == queryFormatGeoJSON && mode != "" && mode != queryModeRows {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("geojson format needs rows mode, not %q", query.Mode))
+		return
+	}
+	switch mode {
+	case "", queryModeRows, queryModeStats, queryModeGeohash:
+	case queryModeQuality:
+		if isUnionQuery(query) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("quality mode reports on a single sheet"))
+			return
+		}
+		report, err := inst.qualityReport(ctx, query.SheetID, limit, skip, opts)
+		if err != nil {
+			backend.Logger.Error("Quality report failed", "sheetId", query.SheetID, "err", err)
+			writeError(w, statusFromError(err), err)
+			return
+		}
+		if format == queryFormatFrame {
+			writeFrame(w, query, report, qualityFields, "")
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResponse{
+			"rows":    report,
+			"refId":   query.RefID,
+			"sheetId": query.SheetID,
+			"fields":  qualityFields,
+		})
+		return
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown query mode %q", query.Mode))
+		return
+	}
+
 	backend.Logger.Info("Query rows", "sheetId", query.SheetID, "refId", query.RefID, "limit", limit, "skip", skip)
 
-	rows, err := inst.listRows(ctx, query.SheetID, limit, skip)
+	var sheet sheetData
+	if isUnionQuery(query) {
+		sheet, err = inst.loadUnion(ctx, query, limit, skip, opts)
+	} else {
+		sheet, err = inst.loadSheet(ctx, query.SheetID, limit, skip, opts)
+	}
 	if err != nil {
 		backend.Logger.Error("Query rows failed", "sheetId", query.SheetID, "err", err)
 		writeError(w, statusFromError(err), err)
 		return
 	}
+	observeRows("fetched", len(sheet.rows))
 
-	fieldsMeta, fieldErr := inst.getFields(ctx, query.SheetID)
-	if fieldErr != nil {
-		backend.Logger.Warn("Query failed to fetch field metadata", "sheetId", query.SheetID, "err", fieldErr)
+	if query.Join != nil {
+		sheet, err = inst.joinSheet(ctx, sheet, *query.Join, opts)
+		if err != nil {
+			backend.Logger.Error("Query join failed", "sheetId", query.SheetID, "joinSheetId", query.Join.SheetID, "err", err)
+			writeError(w, statusFromError(err), err)
+			return
+		}
 	}
 
-	descList, descMap := buildFieldDescriptors(fieldsMeta, rows)
+	sheet, err = applyFlatten(sheet, query.Flatten)
+	if err != nil {
+		backend.Logger.Warn("Query flatten invalid", "sheetId", query.SheetID, "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
+	sheet = inst.proxyMediaURLs(addLinkColumns(sheet), mediaPath(ctx))
+
+	sheet, err = applyComputedColumns(sheet, query.Computed, opts)
+	if err != nil {
+		backend.Logger.Warn("Query computed columns invalid", "sheetId", query.SheetID, "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
+
+	descList := sheet.descList
+	normalizedRows := sheet.rows
 
 	originalTimeInput := strings.TrimSpace(query.TimeField)
 	effectiveTimeField := ""
 	if originalTimeInput != "" {
-		if resolvedField, ok := resolveTimeField(originalTimeInput, descList, rows); ok {
+		if resolvedField, ok := resolveTimeField(originalTimeInput, descList, normalizedRows); ok {
 			effectiveTimeField = resolvedField
 		} else {
 			backend.Logger.Warn("Requested time field not found", "sheetId", query.SheetID, "timeField", originalTimeInput)
@@ -340,19 +503,109 @@ func (d *orcaDatasource) handleQuery(w http.ResponseWriter, r *http.Request) {
 
 	query.TimeField = effectiveTimeField
 
-	rowsWithGeo, geoSuccess := extendRowsWithGeo(rows, descMap)
-	descList, descMap = extendFieldDescriptorsForGeo(descList, descMap, geoSuccess)
-	normalizedRows := normalizeRows(rowsWithGeo, descMap)
+	filtered := applyClientFilters(normalizedRows, query, effectiveTimeField, opts)
+	filtered, descList, err = applySpatialFilter(filtered, descList, sheet.columns, query.Spatial)
+	if err != nil {
+		backend.Logger.Warn("Spatial filter failed", "sheetId", query.SheetID, "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
 
-	filtered := applyClientFilters(normalizedRows, query, effectiveTimeField)
+	if mode == queryModeStats {
+		statsDescs := descList
+		if !opts.systemColumns {
+			statsDescs = hideSystemColumns(descList, "")
+		}
+		stats, err := computeStats(filtered, statsDescs, sheet.columns, query.Stats)
+		if err != nil {
+			backend.Logger.Warn("Query stats failed", "sheetId", query.SheetID, "err", err)
+			writeError(w, statusFromError(err), err)
+			return
+		}
+		observeRows("returned", len(stats))
+		if format == queryFormatFrame {
+			writeFrame(w, query, stats, statsFields, "")
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResponse{
+			"rows":    stats,
+			"refId":   query.RefID,
+			"sheetId": query.SheetID,
+			"fields":  statsFields,
+		})
+		return
+	}
+
+	if mode == queryModeGeohash {
+		cells, fields, err := geohashBins(filtered, descList, sheet.columns, query.Geohash)
+		if err != nil {
+			backend.Logger.Warn("Geohash binning failed", "sheetId", query.SheetID, "err", err)
+			writeError(w, statusFromError(err), err)
+			return
+		}
+		observeRows("returned", len(cells))
+		if format == queryFormatFrame {
+			writeFrame(w, query, cells, fields, "")
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResponse{
+			"rows":    cells,
+			"refId":   query.RefID,
+			"sheetId": query.SheetID,
+			"fields":  fields,
+		})
+		return
+	}
+
+	filtered, err = sortRows(filtered, query.Sort, descList, opts)
+	if err == nil {
+		filtered = limitRows(filtered, query.TopN)
+		filtered, descList, effectiveTimeField, err = projectColumns(filtered, descList, query.Columns, effectiveTimeField)
+	}
+	if err != nil {
+		backend.Logger.Warn("Query shaping failed", "sheetId", query.SheetID, "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
+	if !opts.systemColumns && len(query.Columns) == 0 {
+		// Hidden system columns still serve as the time field; selected columns are kept.
+		descList = hideSystemColumns(descList, effectiveTimeField)
+	}
+
+	if format == queryFormatGeoJSON {
+		geoKey, err := resolveGeoField(query.GeoField, descList, sheet.columns, filtered)
+		if err != nil {
+			writeError(w, statusFromError(err), err)
+			return
+		}
+		features, props := buildFeatureCollection(filtered, descList, sheet.columns, geoKey)
+		props = append(props, geoJSONLatField, geoJSONLonField)
+		observeRows("returned", len(features))
+
+		writeJSON(w, http.StatusOK, apiResponse{
+			"type":      "FeatureCollection",
+			"features":  features,
+			"refId":     query.RefID,
+			"sheetId":   query.SheetID,
+			"fields":    buildFieldInfos(props, effectiveTimeField),
+			"timeField": effectiveTimeField,
+		})
+		return
+	}
 
 	fieldInfos := buildFieldInfos(descList, effectiveTimeField)
 	if len(fieldInfos) == 0 {
-		fieldInfos = fallbackFieldInfos(filtered, effectiveTimeField)
+		fieldInfos = fallbackFieldInfos(filtered, sheet.columns, effectiveTimeField, opts)
 	}
 
+	observeRows("returned", len(filtered))
 	backend.Logger.Info("Query rows returned", "sheetId", query.SheetID, "refId", query.RefID, "total", len(normalizedRows), "returned", len(filtered), "timeField", effectiveTimeField)
 
+	if format == queryFormatFrame {
+		writeFrame(w, query, filtered, fieldInfos, effectiveTimeField)
+		return
+	}
+
 	writeJSON(w, http.StatusOK, apiResponse{
 		"rows":      filtered,
 		"refId":     query.RefID,
@@ -407,7 +660,8 @@ func (i *orcaInstance) listSheets(ctx context.Context) ([]orcaSheet, error) {
 	return resp.Data, nil
 }
 
-func (i *orcaInstance) listRows(ctx context.Context, sheetID string, limit, skip int) ([]map[string]any, error) {
+// listRows fetches a page of rows along with their keys in the order the API sent them.
+func (i *orcaInstance) listRows(ctx context.Context, sheetID string, limit, skip int) ([]map[string]any, []string, error) {
 	params := url.Values{}
 	if limit > 0 {
 		params.Set("limit", strconv.Itoa(limit))
@@ -419,10 +673,10 @@ func (i *orcaInstance) listRows(ctx context.Context, sheetID string, limit, skip
 	var resp orcaRowsResponse
 	path := fmt.Sprintf("/sheets/%s/rows", url.PathEscape(sheetID))
 	if err := i.do(ctx, http.MethodGet, path, params, nil, &resp); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return resp.Data, nil
+	return resp.Data, resp.Columns, nil
 }
 
 func (i *orcaInstance) getFields(ctx context.Context, sheetID string) ([]orcaField, error) {
@@ -433,9 +687,11 @@ func (i *orcaInstance) getFields(ctx context.Context, sheetID string) ([]orcaFie
 	i.fieldCacheMu.RLock()
 	if entry, ok := i.fieldCache[sheetID]; ok && time.Since(entry.fetchedAt) < fieldCacheTTL {
 		i.fieldCacheMu.RUnlock()
+		observeCache("fields", true)
 		return entry.fields, nil
 	}
 	i.fieldCacheMu.RUnlock()
+	observeCache("fields", false)
 
 	var resp struct {
 		Data []orcaField `json:"data"`
@@ -456,6 +712,52 @@ func (i *orcaInstance) getFields(ctx context.Context, sheetID string) ([]orcaFie
 	return resp.Data, nil
 }
 
+// loadSheet fetches a page of rows and the field metadata for a sheet and returns
+// the rows normalized against the detected field descriptors, geo columns included.
+func (i *orcaInstance) loadSheet(ctx context.Context, sheetID string, limit, skip int, opts parseOptions) (sheetData, error) {
+	rows, columns, err := i.listRows(ctx, sheetID, limit, skip)
+	if err != nil {
+		return sheetData{}, err
+	}
+	return i.buildSheet(ctx, sheetID, rows, columns, opts), nil
+}
+
+// buildSheet normalizes fetched rows of sheetID against the descriptors detected from
+// the sheet's field metadata and the rows themselves.
+func (i *orcaInstance) buildSheet(ctx context.Context, sheetID string, rows []map[string]any, columns []string, opts parseOptions) sheetData {
+	fieldsMeta, fieldErr := i.getFields(ctx, sheetID)
+	if fieldErr != nil {
+		backend.Logger.Warn("Failed to fetch field metadata", "sheetId", sheetID, "err", fieldErr)
+	}
+	if len(fieldsMeta) == 0 {
+		// Without metadata the columns follow the order the rows arrived in, so every
+		// output path sees the same order on each refresh.
+		fieldsMeta = fieldsFromColumns(sheetColumns(columns))
+	}
+
+	detections := i.cachedDetections(sheetID)
+	descList, descMap := buildFieldDescriptorsCached(fieldsMeta, rows, opts, detections)
+	i.storeDetections(sheetID, detections)
+	descList, descMap = appendSystemDescriptors(descList, descMap, columns, rows, opts)
+
+	rowsWithGeo, geoSuccess := extendRowsWithGeo(rows, descMap)
+	descList, descMap = extendFieldDescriptorsForGeo(descList, descMap, geoSuccess)
+
+	rowsWithBarcodes, barcodeColumns := extendRowsWithBarcodes(rowsWithGeo, descMap)
+	descList, descMap = extendFieldDescriptorsForBarcodes(descList, descMap, barcodeColumns)
+
+	normalized := normalizeRows(rowsWithBarcodes, descMap)
+	descList, descMap = dropOffenders(normalized, descList)
+
+	return sheetData{
+		sheetID:  sheetID,
+		rows:     normalized,
+		descList: descList,
+		descMap:  descMap,
+		columns:  appendDescriptorColumns(columns, descList),
+	}
+}
+
 func (i *orcaInstance) do(ctx context.Context, method, path string, params url.Values, body io.Reader, out any) error {
 	if err := i.validateAPIKey(); err != nil {
 		return err
@@ -475,11 +777,14 @@ func (i *orcaInstance) do(ctx context.Context, method, path string, params url.V
 	req.Header.Set("Authorization", i.authHeader())
 	req.Header.Set("User-Agent", "Grafana-OrcaScan-Plugin/1.0")
 
+	start := time.Now()
 	resp, err := i.httpClient.Do(req)
 	if err != nil {
+		observeUpstream(upstreamEndpoint(path), start, 0)
 		return err
 	}
 	defer resp.Body.Close()
+	observeUpstream(upstreamEndpoint(path), start, resp.StatusCode)
 
 	if resp.StatusCode >= http.StatusBadRequest {
 		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
@@ -512,7 +817,7 @@ func sanitizeSkip(v int) int {
 	return v
 }
 
-func applyClientFilters(rows []map[string]any, query models.OrcaQuery, timeField string) []map[string]any {
+func applyClientFilters(rows []map[string]any, query models.OrcaQuery, timeField string, opts parseOptions) []map[string]any {
 	if len(rows) == 0 {
 		return rows
 	}
@@ -521,12 +826,12 @@ func applyClientFilters(rows []map[string]any, query models.OrcaQuery, timeField
 
 	if timeField != "" {
 		if query.Range.From != nil && *query.Range.From != "" {
-			if parsed, err := parseOrcaTimeString(*query.Range.From); err == nil {
+			if parsed, err := parseOrcaTimeString(*query.Range.From, opts); err == nil {
 				fromTime = &parsed
 			}
 		}
 		if query.Range.To != nil && *query.Range.To != "" {
-			if parsed, err := parseOrcaTimeString(*query.Range.To); err == nil {
+			if parsed, err := parseOrcaTimeString(*query.Range.To, opts); err == nil {
 				toTime = &parsed
 			}
 		}
@@ -540,7 +845,7 @@ func applyClientFilters(rows []map[string]any, query models.OrcaQuery, timeField
 		if timeField != "" && (fromTime != nil || toTime != nil) {
 			val, ok := row[timeField]
 			if ok {
-				if ts, parsed := timeFromValue(val); parsed {
+				if ts, parsed := timeFromValue(val, opts); parsed {
 					if fromTime != nil && ts.Before(*fromTime) {
 						keep = false
 					}
@@ -559,7 +864,13 @@ func applyClientFilters(rows []map[string]any, query models.OrcaQuery, timeField
 	return matches
 }
 
-func buildFieldDescriptors(fields []orcaField, rows []map[string]any) ([]fieldDescriptor, map[string]fieldDescriptor) {
+func buildFieldDescriptors(fields []orcaField, rows []map[string]any, opts parseOptions) ([]fieldDescriptor, map[string]fieldDescriptor) {
+	return buildFieldDescriptorsCached(fields, rows, opts, nil)
+}
+
+// buildFieldDescriptorsCached is buildFieldDescriptors reusing the kind detections in
+// detections and adding new ones to it; a nil map detects every field afresh.
+func buildFieldDescriptorsCached(fields []orcaField, rows []map[string]any, opts parseOptions, detections map[string]kindDetection) ([]fieldDescriptor, map[string]fieldDescriptor) {
 	if len(fields) == 0 {
 		return nil, map[string]fieldDescriptor{}
 	}
@@ -568,21 +879,63 @@ func buildFieldDescriptors(fields []orcaField, rows []map[string]any) ([]fieldDe
 	mapping := make(map[string]fieldDescriptor, len(fields))
 	decimalsMap := computeFieldDecimals(rows)
 	for _, f := range fields {
-		kind := classifyField(f)
-		kind = detectKindFromRows(f.Key, rows, kind)
-
+		fieldOpts := opts.forField(f)
 		descriptor := fieldDescriptor{
 			meta: f,
-			kind: kind,
+			opts: fieldOpts,
 		}
 
-		if kind == fieldKindNumber || kind == fieldKindGeo {
+		kind, forced := fieldOpts.fieldType(f)
+		var dates dateOrderDetection
+		if !forced {
+			cacheKey := detectionCacheKey(f, fieldOpts)
+			detection, cached := detections[cacheKey]
+			if !cached {
+				detection = detectKind(f.Key, rows, classifyField(f), fieldOpts)
+				if detection.kind == fieldKindNumber && fieldOpts.keepBarcodes && isBarcodeFieldName(f) {
+					detection.kind = fieldKindBarcode
+				}
+				if detection.kind == fieldKindTime && fieldOpts.dateOrder == dateOrderAuto {
+					detection.dates = detectDateOrder(sampleValues(f.Key, rows, fieldOpts.sampleSize))
+				}
+				if detections != nil && detection.sampled > 0 {
+					detections[cacheKey] = detection
+				}
+			}
+			kind = detection.kind
+			dates = detection.dates
+			descriptor.confidence = detection.confidence
+		} else if kind == fieldKindTime && fieldOpts.dateOrder == dateOrderAuto {
+			dates = detectDateOrder(sampleValues(f.Key, rows, fieldOpts.sampleSize))
+		}
+		descriptor.kind = kind
+
+		if kind == fieldKindTime && descriptor.opts.dateOrder == dateOrderAuto {
+			if dates.order != dateOrderAuto {
+				descriptor.opts.dateOrder = dates.order
+			}
+			descriptor.dateAmbiguous = dates.ambiguous
+		}
+
+		if kind == fieldKindNumber {
+			decimals, ok, unit := numberColumnInfo(f.Key, rows, fieldOpts)
+			if ok && decimals > 0 {
+				descriptor.decimals = decimals
+				descriptor.hasDecimals = true
+			}
+			descriptor.unit = unitFromFormat(f)
+			if descriptor.unit == "" {
+				descriptor.unit = unit
+			}
+		} else if kind == fieldKindGeo {
 			if d, ok := decimalsMap[f.Key]; ok {
 				if d > 0 {
 					descriptor.decimals = d
 					descriptor.hasDecimals = true
 				}
 			}
+		} else if kind == fieldKindEnum {
+			descriptor.enumValues = enumValues(f.Key, rows)
 		}
 
 		list = append(list, descriptor)
@@ -596,6 +949,8 @@ func classifyField(f orcaField) fieldKind {
 	typ := strings.ToLower(strings.TrimSpace(f.Type))
 
 	switch {
+	case strings.Contains(format, "barcode"), typ == "barcode":
+		return fieldKindBarcode
 	case strings.Contains(format, "true/false"), typ == "boolean":
 		return fieldKindBoolean
 	case strings.Contains(format, "number"), strings.Contains(format, "formula"), typ == "number", typ == "integer", typ == "float", typ == "double":
@@ -610,57 +965,13 @@ func classifyField(f orcaField) fieldKind {
 }
 
 func detectKindFromRows(key string, rows []map[string]any, current fieldKind) fieldKind {
-	if (current != fieldKindString && current != fieldKindGeo) || len(rows) == 0 {
-		return current
-	}
-
-	numeric := true
-	boolean := true
-	timeLike := true
-	geoLike := true
-	seenValue := false
-	for _, row := range rows {
-		val, ok := row[key]
-		if !ok || val == nil {
-			continue
-		}
-
-		seenValue = true
-
-		if numeric && !isNumericValue(val) {
-			numeric = false
-		}
-		if boolean && !isBooleanValue(val) {
-			boolean = false
-		}
-		if timeLike && !isTimeValue(val) {
-			timeLike = false
-		}
-		if geoLike && !isGeoValue(val) {
-			geoLike = false
-		}
-
-		if !numeric && !boolean && !timeLike && !geoLike {
-			break
-		}
-	}
+	return detectKindFromRowsWithOptions(key, rows, current, parseOptions{})
+}
 
-	if !seenValue {
-		return current
-	}
-	if numeric {
-		return fieldKindNumber
-	}
-	if boolean {
-		return fieldKindBoolean
-	}
-	if timeLike {
-		return fieldKindTime
-	}
-	if geoLike {
-		return fieldKindGeo
-	}
-	return current
+// detectKindFromRowsWithOptions is detectKindFromRows using the field's parsing and
+// detection options.
+func detectKindFromRowsWithOptions(key string, rows []map[string]any, current fieldKind, opts parseOptions) fieldKind {
+	return detectKind(key, rows, current, opts).kind
 }
 
 func computeFieldDecimals(rows []map[string]any) map[string]int {
@@ -703,38 +1014,19 @@ func detectGeoColumns(rows []map[string]any) map[string]geoColumnInfo {
 }
 
 func decimalsFromValue(val any) (int, bool) {
+	return decimalsFromValueWithOptions(val, parseOptions{})
+}
+
+func decimalsFromValueWithOptions(val any, opts parseOptions) (int, bool) {
 	switch v := val.(type) {
 	case string:
-		trimmed := strings.TrimSpace(v)
-		if trimmed == "" {
-			return 0, false
-		}
-		trimmed = strings.TrimPrefix(trimmed, "+")
-		trimmed = strings.TrimSpace(trimmed)
-		negative := strings.HasPrefix(trimmed, "-")
-		if negative {
-			trimmed = strings.TrimPrefix(trimmed, "-")
-		}
-		if trimmed == "" {
-			return 0, false
-		}
-		if strings.ContainsAny(trimmed, "eE") {
-			if _, err := strconv.ParseFloat(v, 64); err == nil {
-				return 0, true
-			}
-			return 0, false
-		}
-		if idx := strings.Index(trimmed, "."); idx >= 0 {
-			suffix := trimmed[idx+1:]
-			return len(suffix), true
-		}
-		if _, err := strconv.ParseFloat(v, 64); err == nil {
-			return 0, true
+		if parsed, ok := parseNumberString(v, opts.decimalSeparator); ok {
+			return parsed.decimals, true
 		}
 		return 0, false
 	case json.Number:
 		if s := v.String(); s != "" {
-			return decimalsFromValue(s)
+			return decimalsFromValueWithOptions(s, parseOptions{})
 		}
 	case float64, float32:
 		// Unable to determine reliably; skip
@@ -746,7 +1038,7 @@ func decimalsFromValue(val any) (int, bool) {
 	return 0, false
 }
 
-func isNumericValue(val any) bool {
+func isNumericValue(val any, opts parseOptions) bool {
 	switch v := val.(type) {
 	case float64, float32, int, int32, int64, uint, uint32, uint64:
 		return true
@@ -755,14 +1047,8 @@ func isNumericValue(val any) bool {
 			return true
 		}
 	case string:
-		trimmed := strings.TrimSpace(v)
-		if trimmed == "" {
-			return false
-		}
-		normalized := strings.ReplaceAll(trimmed, ",", "")
-		if _, err := strconv.ParseFloat(normalized, 64); err == nil {
-			return true
-		}
+		_, ok := parseNumberString(v, opts.decimalSeparator)
+		return ok
 	}
 	return false
 }
@@ -784,7 +1070,10 @@ func isBooleanValue(val any) bool {
 	return false
 }
 
-func isTimeValue(val any) bool {
+// isTimeValue reports whether val parses as a time. Bare numbers, including digit-only
+// strings, only count when the field options say how to read them, since any number is
+// a plausible epoch.
+func isTimeValue(val any, opts parseOptions) bool {
 	switch v := val.(type) {
 	case time.Time:
 		return true
@@ -793,10 +1082,20 @@ func isTimeValue(val any) bool {
 		if trimmed == "" {
 			return false
 		}
-		if !strings.ContainsAny(trimmed, "-/:T ") {
+		if !strings.ContainsAny(trimmed, "0123456789") {
+			return false
+		}
+		if len(opts.layouts) == 0 && opts.numericTime == numericTimeAuto && !strings.ContainsAny(trimmed, "-/:T ") {
+			return false
+		}
+		if _, err := parseOrcaTimeString(trimmed, opts); err == nil {
+			return true
+		}
+	default:
+		if opts.numericTime == numericTimeAuto {
 			return false
 		}
-		if _, err := parseOrcaTimeString(trimmed); err == nil {
+		if _, err := parseValueToTime(v, opts); err == nil {
 			return true
 		}
 	}
@@ -818,7 +1117,7 @@ func normalizeRows(rows []map[string]any, descriptors map[string]fieldDescriptor
 		out := make(map[string]any, len(row))
 		for key, val := range row {
 			if desc, ok := descriptors[key]; ok {
-				out[key] = normalizeValue(val, desc.kind)
+				out[key] = normalizeValue(val, desc.kind, desc.opts)
 			} else {
 				out[key] = val
 			}
@@ -828,27 +1127,33 @@ func normalizeRows(rows []map[string]any, descriptors map[string]fieldDescriptor
 	return normalized
 }
 
-func normalizeValue(value any, kind fieldKind) any {
+func normalizeValue(value any, kind fieldKind, opts parseOptions) any {
 	if value == nil {
 		return nil
 	}
 
 	switch kind {
 	case fieldKindNumber:
-		return normalizeNumber(value)
+		return normalizeNumber(value, opts)
 	case fieldKindBoolean:
 		return normalizeBoolean(value)
 	case fieldKindTime:
-		if ts, err := parseValueToTime(value); err == nil {
+		if ts, err := parseValueToTime(value, opts); err == nil {
 			return ts
 		}
 		return value
+	case fieldKindEnum:
+		return enumValue(value)
+	case fieldKindJSON:
+		return normalizeJSON(value)
+	case fieldKindBarcode:
+		return normalizeBarcode(value)
 	default:
 		return value
 	}
 }
 
-func normalizeNumber(value any) any {
+func normalizeNumber(value any, opts parseOptions) any {
 	switch v := value.(type) {
 	case float64, float32, int64, int32, int, uint, uint32, uint64:
 		return toFloat64(v)
@@ -857,10 +1162,8 @@ func normalizeNumber(value any) any {
 			return f
 		}
 	case string:
-		if trimmed := strings.TrimSpace(v); trimmed != "" {
-			if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
-				return f
-			}
+		if parsed, ok := parseNumberString(v, opts.decimalSeparator); ok {
+			return parsed.value
 		}
 	}
 	return value
@@ -908,64 +1211,125 @@ func normalizeBoolean(value any) any {
 	return value
 }
 
-func parseValueToTime(value any) (time.Time, error) {
+func parseValueToTime(value any, opts parseOptions) (time.Time, error) {
 	switch v := value.(type) {
 	case time.Time:
 		return v, nil
 	case string:
-		return parseOrcaTimeString(v)
+		return parseOrcaTimeString(v, opts)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return timeFromNumber(f, opts)
+	case float64, float32, int, int32, int64, uint, uint32, uint64:
+		return timeFromNumber(toFloat64(v), opts)
 	default:
 		return time.Time{}, fmt.Errorf("unsupported time value")
 	}
 }
 
-func parseOrcaTimeString(v string) (time.Time, error) {
+// parseOrcaTimeString parses the time layouts Orca produces. Values without a zone are
+// interpreted in the location from opts.
+func parseOrcaTimeString(v string, opts parseOptions) (time.Time, error) {
 	v = strings.TrimSpace(v)
 	if v == "" {
 		return time.Time{}, fmt.Errorf("empty time")
 	}
 
-	layouts := []string{
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return timeFromNumber(f, opts)
+	}
+	v = normalizeMeridiem(v)
+
+	for _, layout := range opts.timeLayouts() {
+		if ts, err := time.ParseInLocation(layout, v, opts.loc()); err == nil {
+			return ts, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse time")
+}
+
+var (
+	// isoTimeLayouts are unambiguous and tried for every date order.
+	isoTimeLayouts = []string{
 		time.RFC3339Nano,
 		time.RFC3339,
 		"2006-01-02T15:04:05",
 		"2006-01-02 15:04:05",
 		"2006-01-02 15:04",
+		"2006-01-02 3:04:05 PM",
+		"2006-01-02 3:04 PM",
 		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/01/02 15:04",
+		"2006/01/02 3:04:05 PM",
+		"2006/01/02 3:04 PM",
+		time.RFC1123,
+		time.RFC1123Z,
+		time.RFC850,
+		time.RFC822,
+		time.RFC822Z,
+		time.ANSIC,
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05",
+		"2 Jan 2006 15:04",
+		"2 Jan 2006 3:04 PM",
+		"2 Jan 2006",
+		"2 January 2006 15:04",
+		"2 January 2006",
+		"2-Jan-2006",
+		"Jan 2, 2006 15:04:05",
+		"Jan 2, 2006 3:04:05 PM",
+		"Jan 2, 2006 3:04 PM",
+		"Jan 2, 2006",
+		"January 2, 2006 3:04 PM",
+		"January 2, 2006",
+		"Jan 2 2006",
+	}
+	dmyTimeLayouts = []string{
 		"02/01/2006 15:04:05",
 		"02/01/2006 15:04",
+		"02/01/2006 3:04:05 PM",
+		"02/01/2006 3:04 PM",
 		"02/01/2006",
 		"02-01-2006 15:04:05",
 		"02-01-2006 15:04",
 		"02-01-2006",
+	}
+	mdyTimeLayouts = []string{
 		"01/02/2006 15:04:05",
 		"01/02/2006 15:04",
+		"01/02/2006 3:04:05 PM",
+		"01/02/2006 3:04 PM",
 		"01/02/2006",
-		"2006/01/02 15:04:05",
-		"2006/01/02 15:04",
+		"01-02-2006 15:04:05",
+		"01-02-2006 15:04",
+		"01-02-2006",
 	}
+)
 
-	for _, layout := range layouts {
-		if ts, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
-			return ts, nil
-		}
+func (o parseOptions) timeLayouts() []string {
+	layouts := append([]string(nil), o.layouts...)
+	layouts = append(layouts, isoTimeLayouts...)
+	switch o.dateOrder {
+	case dateOrderDMY:
+		layouts = append(layouts, dmyTimeLayouts...)
+	case dateOrderMDY:
+		layouts = append(layouts, mdyTimeLayouts...)
+	case dateOrderAuto:
+		layouts = append(layouts, dmyTimeLayouts...)
+		layouts = append(layouts, mdyTimeLayouts...)
 	}
-
-	return time.Time{}, fmt.Errorf("unable to parse time")
+	return layouts
 }
 
-func timeFromValue(value any) (time.Time, bool) {
-	switch v := value.(type) {
-	case time.Time:
-		return v, true
-	case string:
-		if ts, err := parseOrcaTimeString(v); err == nil {
-			return ts, true
-		}
-		return time.Time{}, false
-	default:
-		return time.Time{}, false
-	}
+func timeFromValue(value any, opts parseOptions) (time.Time, bool) {
+	ts, err := parseValueToTime(value, opts)
+	return ts, err == nil
 }
 
 func firstTimeField(descriptors []fieldDescriptor) string {
@@ -1005,8 +1369,25 @@ func buildFieldInfos(descriptors []fieldDescriptor, timeField string) []models.F
 			GrafanaType: desc.kind.grafanaType(),
 			IsTime:      desc.kind == fieldKindTime,
 			Decimals:    decimalsPtr,
+			Config:      fieldConfig(desc),
+		}
+
+		if field.Config != nil && field.Config.Decimals != nil {
+			d := int(*field.Config.Decimals)
+			field.Decimals = &d
+		}
+
+		if desc.kind == fieldKindTime && desc.opts.dateOrder != dateOrderAuto {
+			field.DateOrder = desc.opts.dateOrder.String()
+			field.DateOrderAmbiguous = desc.dateAmbiguous
 		}
 
+		if desc.confidence > 0 {
+			confidence := math.Round(desc.confidence*1000) / 1000
+			field.Confidence = &confidence
+		}
+		field.Offenders = desc.offenders
+
 		if timeField != "" && desc.meta.Key == timeField {
 			selectedIndex = idx
 		}
@@ -1023,22 +1404,14 @@ func buildFieldInfos(descriptors []fieldDescriptor, timeField string) []models.F
 	return fields
 }
 
-func fallbackFieldInfos(rows []map[string]any, timeField string) []models.Field {
+// fallbackFieldInfos describes rows without descriptors, detecting each column's kind
+// from its values. Fields follow columns, the sheet's own order, with timeField first.
+func fallbackFieldInfos(rows []map[string]any, columns []string, timeField string, opts parseOptions) []models.Field {
 	if len(rows) == 0 {
 		return nil
 	}
 
-	seen := make(map[string]struct{})
-	keys := make([]string, 0)
-	for _, row := range rows {
-		for key := range row {
-			if _, exists := seen[key]; exists {
-				continue
-			}
-			seen[key] = struct{}{}
-			keys = append(keys, key)
-		}
-	}
+	keys := append([]string(nil), columns...)
 
 	decimalsMap := computeFieldDecimals(rows)
 	geoMap := detectGeoColumns(rows)
@@ -1055,7 +1428,14 @@ func fallbackFieldInfos(rows []map[string]any, timeField string) []models.Field
 	fields := make([]models.Field, 0, len(keys))
 	added := map[string]struct{}{}
 	for _, key := range keys {
-		kind := detectKindFromRows(key, rows, fieldKindString)
+		f := orcaField{Key: key, Label: key}
+		kind, forced := opts.fieldType(f)
+		if !forced {
+			kind = detectKindFromRows(key, rows, fieldKindString)
+			if kind == fieldKindNumber && opts.keepBarcodes && isBarcodeFieldName(f) {
+				kind = fieldKindBarcode
+			}
+		}
 		var decimalsPtr *int
 		if kind == fieldKindNumber {
 			if d, ok := decimalsMap[key]; ok && d > 0 {
@@ -1063,13 +1443,17 @@ func fallbackFieldInfos(rows []map[string]any, timeField string) []models.Field
 				decimalsPtr = &dCopy
 			}
 		}
-		fields = append(fields, models.Field{
+		field := models.Field{
 			Key:         key,
 			Label:       key,
 			GrafanaType: kind.grafanaType(),
 			IsTime:      key == timeField || kind == fieldKindTime,
 			Decimals:    decimalsPtr,
-		})
+		}
+		if kind == fieldKindEnum {
+			field.Config = fieldConfig(fieldDescriptor{meta: f, kind: kind, enumValues: enumValues(key, rows)})
+		}
+		fields = append(fields, field)
 		added[key] = struct{}{}
 
 		if kind == fieldKindGeo {
@@ -1128,20 +1512,34 @@ func extendRowsWithGeo(rows []map[string]any, descriptors map[string]fieldDescri
 				continue
 			}
 
-			lat, lon, latDec, lonDec, ok := parseGeoValue(row[key])
+			point, ok := parseGeoPoint(row[key])
 			if !ok {
 				continue
 			}
 
-			out[fmt.Sprintf("%s_lat", key)] = lat
-			out[fmt.Sprintf("%s_lon", key)] = lon
+			out[fmt.Sprintf("%s_lat", key)] = point.lat
+			out[fmt.Sprintf("%s_lon", key)] = point.lon
 
 			info := success[key]
-			if latDec > info.latDecimals {
-				info.latDecimals = latDec
+			if point.latDecimals > info.latDecimals {
+				info.latDecimals = point.latDecimals
 			}
-			if lonDec > info.lonDecimals {
-				info.lonDecimals = lonDec
+			if point.lonDecimals > info.lonDecimals {
+				info.lonDecimals = point.lonDecimals
+			}
+			if point.hasAlt {
+				out[fmt.Sprintf("%s_alt", key)] = point.alt
+				info.hasAlt = true
+				if point.altDecimals > info.altDecimals {
+					info.altDecimals = point.altDecimals
+				}
+			}
+			if point.hasAccuracy {
+				out[fmt.Sprintf("%s_accuracy", key)] = point.accuracy
+				info.hasAccuracy = true
+				if point.accuracyDecimals > info.accuracyDecimals {
+					info.accuracyDecimals = point.accuracyDecimals
+				}
 			}
 			success[key] = info
 		}
@@ -1152,46 +1550,14 @@ func extendRowsWithGeo(rows []map[string]any, descriptors map[string]fieldDescri
 	return extended, success
 }
 
+// parseGeoValue returns the latitude, longitude and their decimals of a coordinate in
+// any form parseGeoPoint accepts.
 func parseGeoValue(val any) (float64, float64, int, int, bool) {
-	var raw string
-	switch v := val.(type) {
-	case string:
-		raw = v
-	case fmt.Stringer:
-		raw = v.String()
-	default:
-		raw = fmt.Sprint(v)
-	}
-
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return 0, 0, 0, 0, false
-	}
-
-	raw = strings.ReplaceAll(raw, ";", ",")
-	raw = strings.ReplaceAll(raw, ", ", ",")
-	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '|' })
-	if len(parts) != 2 {
-		return 0, 0, 0, 0, false
-	}
-
-	latToken := strings.TrimSpace(parts[0])
-	lonToken := strings.TrimSpace(parts[1])
-
-	lat, err1 := strconv.ParseFloat(latToken, 64)
-	lon, err2 := strconv.ParseFloat(lonToken, 64)
-	if err1 != nil || err2 != nil {
-		return 0, 0, 0, 0, false
-	}
-
-	if math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+	point, ok := parseGeoPoint(val)
+	if !ok {
 		return 0, 0, 0, 0, false
 	}
-
-	latDec := decimalsInComponent(latToken)
-	lonDec := decimalsInComponent(lonToken)
-
-	return lat, lon, latDec, lonDec, true
+	return point.lat, point.lon, point.latDecimals, point.lonDecimals, true
 }
 
 func decimalsInComponent(token string) int {
@@ -1250,6 +1616,40 @@ func extendFieldDescriptorsForGeo(list []fieldDescriptor, mapping map[string]fie
 			extended = append(extended, latDesc, lonDesc)
 			newMapping[latField.Key] = latDesc
 			newMapping[lonField.Key] = lonDesc
+
+			if info.hasAlt {
+				altDesc := fieldDescriptor{
+					meta: orcaField{
+						Key:   fmt.Sprintf("%s_alt", desc.meta.Key),
+						Label: fmt.Sprintf("%s Altitude", labelOrKey(desc.meta)),
+						Type:  "number",
+					},
+					kind: fieldKindNumber,
+				}
+				if info.altDecimals > 0 {
+					altDesc.decimals = info.altDecimals
+					altDesc.hasDecimals = true
+				}
+				extended = append(extended, altDesc)
+				newMapping[altDesc.meta.Key] = altDesc
+			}
+
+			if info.hasAccuracy {
+				accuracyDesc := fieldDescriptor{
+					meta: orcaField{
+						Key:   fmt.Sprintf("%s_accuracy", desc.meta.Key),
+						Label: fmt.Sprintf("%s Accuracy", labelOrKey(desc.meta)),
+						Type:  "number",
+					},
+					kind: fieldKindNumber,
+				}
+				if info.accuracyDecimals > 0 {
+					accuracyDesc.decimals = info.accuracyDecimals
+					accuracyDesc.hasDecimals = true
+				}
+				extended = append(extended, accuracyDesc)
+				newMapping[accuracyDesc.meta.Key] = accuracyDesc
+			}
 		}
 	}
 
@@ -1318,6 +1718,12 @@ func trimQuotes(value string) string {
 }
 
 func resolveTimeField(input string, descriptors []fieldDescriptor, rows []map[string]any) (string, bool) {
+	return resolveFieldKey(input, descriptors, rows)
+}
+
+// resolveFieldKey maps user input to a column key, matching keys first, then labels,
+// then keys seen in rows, and finally canonicalized keys and labels.
+func resolveFieldKey(input string, descriptors []fieldDescriptor, rows []map[string]any) (string, bool) {
 	if input == "" {
 		return "", false
 	}
@@ -1345,9 +1751,37 @@ func resolveTimeField(input string, descriptors []fieldDescriptor, rows []map[st
 		}
 	}
 
+	canonical := canonicalizeFieldKey(input)
+	if canonical == "" {
+		return "", false
+	}
+
+	for _, desc := range descriptors {
+		if canonicalizeFieldKey(desc.meta.Key) == canonical || canonicalizeFieldKey(desc.meta.Label) == canonical {
+			return desc.meta.Key, true
+		}
+	}
+
 	return "", false
 }
 
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e requestError) Error() string { return e.msg }
+
+func (e requestError) Status() int { return e.status }
+
+func newRequestError(format string, args ...any) error {
+	return requestError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+func newNotFoundError(format string, args ...any) error {
+	return requestError{status: http.StatusNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
 func statusFromError(err error) int {
 	var httpErr interface{ Status() int }
 	if errors.As(err, &httpErr) {
@@ -1,6 +1,11 @@
 package main
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
 
 func TestComputeFieldDecimals(t *testing.T) {
 	rows := []map[string]any{
@@ -127,3 +132,37 @@ func TestTrimQuotes(t *testing.T) {
 		}
 	}
 }
+
+func TestParseOrcaTimeStringLocation(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	opts := parseOptions{location: sydney}
+
+	got, err := parseOrcaTimeString("2025-09-01 08:00", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2025, 8, 31, 22, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected naive time in Sydney (%s), got %s", want, got.UTC())
+	}
+
+	got, err = parseOrcaTimeString("2025-09-01T08:00:00Z", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected explicit zone to win, got %s", got.UTC())
+	}
+
+	rows := []map[string]any{
+		{"when": "2025-09-01 08:00"},
+		{"when": "2025-09-01 12:00"},
+	}
+	from := "2025-09-01T00:00:00Z"
+	query := models.OrcaQuery{Range: models.QueryRange{From: &from}}
+	if filtered := applyClientFilters(rows, query, "when", opts); len(filtered) != 1 {
+		t.Fatalf("expected the 08:00 Sydney row to fall before the range, got %v", filtered)
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "image/gif" // registers GIF decoding for thumbnails
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	mediaMaxBytes       = 20 << 20 // largest asset the proxy fetches
+	mediaCacheMaxBytes  = 64 << 20 // total size of cached assets per instance
+	mediaCacheItemBytes = 2 << 20  // largest asset kept in the cache
+	mediaCacheTTL       = 10 * time.Minute
+	mediaMaxRedirects   = 5
+
+	minThumbnailSize   = 16
+	maxThumbnailSize   = 1024
+	maxThumbnailPixels = 8_000_000 // larger images are served as they are
+	thumbnailWorkers   = 4         // images decoded at once across all instances
+)
+
+// thumbnailSlots bounds concurrent thumbnail work, which holds a decoded image in memory.
+var thumbnailSlots = make(chan struct{}, thumbnailWorkers)
+
+// mediaEntry is a fetched asset, resized when a thumbnail was asked for.
+type mediaEntry struct {
+	key         string
+	contentType string
+	body        []byte
+	fetchedAt   time.Time
+}
+
+// mediaCache is a least-recently-used cache of assets bounded by their total size.
+type mediaCache struct {
+	mu    sync.Mutex
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+	size  int
+}
+
+func newMediaCache() *mediaCache {
+	return &mediaCache{order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *mediaCache) get(key string) (mediaEntry, bool) {
+	if c == nil {
+		return mediaEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return mediaEntry{}, false
+	}
+	entry := elem.Value.(mediaEntry)
+	if time.Since(entry.fetchedAt) >= mediaCacheTTL {
+		c.remove(elem)
+		return mediaEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *mediaCache) put(entry mediaEntry) {
+	if c == nil || len(entry.body) > mediaCacheItemBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[entry.key]; ok {
+		c.remove(elem)
+	}
+	c.items[entry.key] = c.order.PushFront(entry)
+	c.size += len(entry.body)
+	for c.size > mediaCacheMaxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *mediaCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(mediaEntry)
+	delete(c.items, entry.key)
+	c.size -= len(entry.body)
+}
+
+// mediaAllowed reports whether u may be fetched with the instance's API key: it must be
+// on exactly the configured API host, over the API's scheme or HTTPS. Sibling hosts are
+// refused, since the parent domain may be shared with other tenants.
+func (i *orcaInstance) mediaAllowed(u *url.URL) bool {
+	base, err := url.Parse(i.baseURL)
+	if err != nil || u == nil || u.User != nil {
+		return false
+	}
+	if u.Scheme != "https" && u.Scheme != base.Scheme {
+		return false
+	}
+	return base.Host != "" && strings.EqualFold(u.Host, base.Host)
+}
+
+// fetchMedia returns the asset at raw, fetched with the instance's Authorization header
+// and scaled to fit size pixels when size is set and the asset is an image.
+func (i *orcaInstance) fetchMedia(ctx context.Context, raw string, size int) (mediaEntry, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return mediaEntry{}, newRequestError("invalid media url %q", raw)
+	}
+	if !i.mediaAllowed(u) {
+		return mediaEntry{}, requestError{status: http.StatusForbidden, msg: fmt.Sprintf("media host %q is not the configured Orca Scan host", u.Host)}
+	}
+
+	key := u.String() + "|" + strconv.Itoa(size)
+	if entry, ok := i.mediaCache.get(key); ok {
+		observeCache("media", true)
+		return entry, nil
+	}
+	observeCache("media", false)
+
+	client := *i.httpClient
+	client.Timeout = 30 * time.Second
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= mediaMaxRedirects {
+			return errors.New("too many redirects")
+		}
+		if !i.mediaAllowed(req.URL) {
+			return fmt.Errorf("redirect to %q is not allowed", req.URL.Host)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return mediaEntry{}, err
+	}
+	req.Header.Set("Authorization", i.authHeader())
+	req.Header.Set("User-Agent", "Grafana-OrcaScan-Plugin/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		observeUpstream("media", start, 0)
+		return mediaEntry{}, fmt.Errorf("orcascan api: GET media: %w", err)
+	}
+	defer resp.Body.Close()
+	observeUpstream("media", start, resp.StatusCode)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return mediaEntry{}, newNotFoundError("media %q not found", u.String())
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return mediaEntry{}, fmt.Errorf("orcascan api: GET media returned %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, mediaMaxBytes+1))
+	if err != nil {
+		return mediaEntry{}, fmt.Errorf("orcascan api: GET media: %w", err)
+	}
+	if len(body) > mediaMaxBytes {
+		return mediaEntry{}, fmt.Errorf("orcascan api: media is larger than %d MB", mediaMaxBytes>>20)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	if size > 0 && strings.HasPrefix(contentType, "image/") {
+		select {
+		case thumbnailSlots <- struct{}{}:
+		case <-ctx.Done():
+			return mediaEntry{}, ctx.Err()
+		}
+		thumb, thumbType, err := thumbnail(body, size)
+		<-thumbnailSlots
+		if err == nil {
+			body, contentType = thumb, thumbType
+		} else {
+			backend.Logger.Debug("Media thumbnail skipped", "url", u.String(), "err", err)
+		}
+	}
+
+	entry := mediaEntry{key: key, contentType: contentType, body: body, fetchedAt: time.Now()}
+	i.mediaCache.put(entry)
+	return entry, nil
+}
+
+// thumbnail scales an image down to fit a size by size box, averaging the pixels each
+// output pixel covers straight from the decoded image. PNG and GIF images stay PNG to
+// keep transparency; the rest become JPEG. Images that already fit are returned
+// unchanged, and ones over maxThumbnailPixels are refused before decoding.
+func thumbnail(body []byte, size int) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, "", fmt.Errorf("image is %dx%d pixels", cfg.Width, cfg.Height)
+	}
+	if cfg.Width <= size && cfg.Height <= size {
+		return body, "image/" + format, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	w, h := size, srcH*size/srcW
+	if srcH > srcW {
+		w, h = srcW*size/srcH, size
+	}
+	w, h = max(w, 1), max(h, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*srcH/h, max((y+1)*srcH/h, y*srcH/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*srcW/w, max((x+1)*srcW/w, x*srcW/w+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			off := dst.PixOffset(x, y)
+			dst.Pix[off] = uint8(r / n >> 8)
+			dst.Pix[off+1] = uint8(g / n >> 8)
+			dst.Pix[off+2] = uint8(b / n >> 8)
+			dst.Pix[off+3] = uint8(a / n >> 8)
+		}
+	}
+
+	var buf bytes.Buffer
+	if format == "png" || format == "gif" {
+		err = png.Encode(&buf, dst)
+		return buf.Bytes(), "image/png", err
+	}
+	err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	return buf.Bytes(), "image/jpeg", err
+}
+
+// mediaPath returns the proxy path for the datasource serving ctx, or "" outside a
+// datasource request. It keeps the sub-path of Grafana's app URL, so instances served
+// from a sub-path (root_url with a path, serve_from_sub_path) resolve it too.
+func mediaPath(ctx context.Context) string {
+	settings := backend.PluginConfigFromContext(ctx).DataSourceInstanceSettings
+	if settings == nil || settings.UID == "" {
+		return ""
+	}
+
+	prefix := ""
+	if appURL, err := backend.GrafanaConfigFromContext(ctx).AppURL(); err == nil {
+		if u, err := url.Parse(appURL); err == nil {
+			prefix = strings.TrimRight(u.Path, "/")
+		}
+	}
+	return prefix + "/api/datasources/uid/" + url.PathEscape(settings.UID) + "/resources/media"
+}
+
+// proxyMediaURLs points the values of link columns that are on the Orca host at the
+// /media resource under path, so the browser loads them with the API key.
+func (i *orcaInstance) proxyMediaURLs(sheet sheetData, path string) sheetData {
+	if path == "" {
+		return sheet
+	}
+
+	var keys []string
+	for _, desc := range sheet.descList {
+		if desc.link {
+			keys = append(keys, desc.meta.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return sheet
+	}
+
+	rows := sheet.rows
+	copied := false
+	for idx, row := range rows {
+		for _, key := range keys {
+			s, ok := row[key].(string)
+			if !ok {
+				continue
+			}
+			u, err := url.Parse(strings.TrimSpace(s))
+			if err != nil || !i.mediaAllowed(u) {
+				continue
+			}
+			if !copied {
+				rows = copyRows(rows)
+				copied = true
+			}
+			rows[idx][key] = path + "?url=" + url.QueryEscape(u.String())
+		}
+	}
+	sheet.rows = rows
+	return sheet
+}
+
+// handleMedia serves GET /media?url=<asset>[&size=<pixels>], fetching an asset on the
+// Orca host with the API key and optionally scaling images down to a thumbnail.
+func (d *orcaDatasource) handleMedia(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	inst, err := d.instanceFromRequest(r)
+	if err != nil {
+		backend.Logger.Error("Media failed to resolve instance", "err", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := inst.validateAPIKey(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := r.URL.Query()
+	raw := strings.TrimSpace(params.Get("url"))
+	if raw == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+	size := 0
+	if s := strings.TrimSpace(params.Get("size")); s != "" {
+		size, err = strconv.Atoi(s)
+		if err != nil || size < minThumbnailSize || size > maxThumbnailSize {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("size must be between %d and %d", minThumbnailSize, maxThumbnailSize))
+			return
+		}
+	}
+
+	entry, err := inst.fetchMedia(ctx, raw, size)
+	if err != nil {
+		backend.Logger.Warn("Media fetch failed", "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", entry.contentType)
+	header.Set("Content-Length", strconv.Itoa(len(entry.body)))
+	header.Set("Cache-Control", "private, max-age=600")
+	// Assets are served from Grafana's origin, so nothing in them may run there.
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "default-src 'none'; img-src 'self' data:; style-src 'unsafe-inline'; sandbox")
+	if !strings.HasPrefix(entry.contentType, "image/") || strings.Contains(entry.contentType, "svg") {
+		header.Set("Content-Disposition", "attachment")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(entry.body)
+}
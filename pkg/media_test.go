@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMediaAllowed(t *testing.T) {
+	inst := &orcaInstance{baseURL: defaultBaseURL}
+	shared := &orcaInstance{baseURL: "https://orca-eu.herokuapp.com/v1"}
+	if shared.mediaAllowed(&url.URL{Scheme: "https", Host: "attacker.herokuapp.com", Path: "/x.png"}) {
+		t.Fatal("expected sibling hosts on a shared domain to be refused")
+	}
+	cases := map[string]bool{
+		"https://api.orcascan.com/v1/files/a.jpg": true,
+		"https://API.orcascan.com/v1/files/a.jpg": true,
+		"https://cdn.orcascan.com/a.jpg":          false,
+		"https://orcascan.com/a.jpg":              false,
+		"http://cdn.orcascan.com/a.jpg":           false,
+		"https://api.orcascan.com:8443/a.jpg":     false,
+		"https://evilorcascan.com/a.jpg":          false,
+		"https://orcascan.com.evil.io/a.jpg":      false,
+		"https://user:pw@api.orcascan.com/a.jpg":  false,
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		if got := inst.mediaAllowed(u); got != want {
+			t.Errorf("%s: got %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestThumbnailLimits(t *testing.T) {
+	out, contentType, err := thumbnail(testPNG(t, 300, 100), 30)
+	if err != nil || contentType != "image/png" {
+		t.Fatalf("unexpected thumbnail %q %v", contentType, err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil || img.Bounds().Dx() != 30 || img.Bounds().Dy() != 10 {
+		t.Fatalf("unexpected thumbnail bounds %v %v", img.Bounds(), err)
+	}
+	if r, _, _, a := img.At(5, 5).RGBA(); r>>8 != 200 || a>>8 != 255 {
+		t.Fatalf("unexpected pixel %v", img.At(5, 5))
+	}
+
+	if _, _, err := thumbnail(testPNG(t, 4000, 2001), 64); err == nil {
+		t.Fatal("expected images over the pixel limit to be refused")
+	}
+}
+
+func TestFetchMedia(t *testing.T) {
+	body := testPNG(t, 40, 20)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/photo.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(body)
+		case "/away":
+			http.Redirect(w, r, "https://example.com/x.png", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	inst := &orcaInstance{baseURL: srv.URL, apiKey: "secret", httpClient: srv.Client(), mediaCache: newMediaCache()}
+	ctx := context.Background()
+
+	entry, err := inst.fetchMedia(ctx, srv.URL+"/photo.png", 0)
+	if err != nil || !bytes.Equal(entry.body, body) || entry.contentType != "image/png" {
+		t.Fatalf("unexpected original %v %q", err, entry.contentType)
+	}
+
+	thumb, err := inst.fetchMedia(ctx, srv.URL+"/photo.png", 16)
+	if err != nil {
+		t.Fatalf("thumbnail: %v", err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(thumb.body))
+	if err != nil || cfg.Width != 16 || cfg.Height != 8 {
+		t.Fatalf("unexpected thumbnail %+v %v", cfg, err)
+	}
+
+	before := hits.Load()
+	if _, err := inst.fetchMedia(ctx, srv.URL+"/photo.png", 16); err != nil || hits.Load() != before {
+		t.Fatalf("expected a cache hit, err %v", err)
+	}
+
+	if _, err := inst.fetchMedia(ctx, "https://example.com/x.png", 0); statusFromError(err) != http.StatusForbidden {
+		t.Fatalf("expected forbidden host, got %v", err)
+	}
+	if _, err := inst.fetchMedia(ctx, srv.URL+"/away", 0); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected redirect off the host to fail, got %v", err)
+	}
+	if _, err := inst.fetchMedia(ctx, srv.URL+"/missing.png", 0); statusFromError(err) != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestMediaCacheEvicts(t *testing.T) {
+	cache := newMediaCache()
+	chunk := make([]byte, mediaCacheItemBytes)
+	for i := 0; i < mediaCacheMaxBytes/mediaCacheItemBytes+1; i++ {
+		cache.put(mediaEntry{key: string(rune('a' + i)), body: chunk})
+	}
+	if _, ok := cache.get("a"); ok {
+		t.Fatal("expected the oldest entry to be evicted")
+	}
+	if cache.size > mediaCacheMaxBytes {
+		t.Fatalf("cache holds %d bytes", cache.size)
+	}
+
+	cache.put(mediaEntry{key: "big", body: make([]byte, mediaCacheItemBytes+1)})
+	if _, ok := cache.get("big"); ok {
+		t.Fatal("expected oversized entries to be skipped")
+	}
+}
+
+func TestMediaPath(t *testing.T) {
+	t.Setenv(backend.AppURL, "")
+	ctx := backend.WithPluginContext(context.Background(), backend.PluginContext{
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: "abc"},
+	})
+	if got := mediaPath(ctx); got != "/api/datasources/uid/abc/resources/media" {
+		t.Fatalf("unexpected path %q", got)
+	}
+
+	sub := backend.WithGrafanaConfig(ctx, backend.NewGrafanaCfg(map[string]string{backend.AppURL: "https://example.com/grafana/"}))
+	if got := mediaPath(sub); got != "/grafana/api/datasources/uid/abc/resources/media" {
+		t.Fatalf("unexpected sub-path %q", got)
+	}
+	if got := mediaPath(context.Background()); got != "" {
+		t.Fatalf("expected no path outside a datasource, got %q", got)
+	}
+}
+
+func TestProxyMediaURLs(t *testing.T) {
+	inst := &orcaInstance{baseURL: defaultBaseURL}
+	sheet := sheetData{
+		rows: []map[string]any{
+			{"Photo": "https://api.orcascan.com/a b.jpg", "Site": "https://example.com"},
+		},
+		descList: []fieldDescriptor{
+			{meta: orcaField{Key: "Photo"}, kind: fieldKindString, link: true},
+			{meta: orcaField{Key: "Site"}, kind: fieldKindString, link: true},
+		},
+	}
+
+	out := inst.proxyMediaURLs(sheet, "/api/datasources/uid/abc/resources/media")
+	if got := out.rows[0]["Photo"]; got != "/api/datasources/uid/abc/resources/media?url=https%3A%2F%2Fapi.orcascan.com%2Fa%2520b.jpg" {
+		t.Fatalf("unexpected proxied url %v", got)
+	}
+	if out.rows[0]["Site"] != "https://example.com" {
+		t.Fatalf("expected other hosts untouched, got %v", out.rows[0]["Site"])
+	}
+	if sheet.rows[0]["Photo"] != "https://api.orcascan.com/a b.jpg" {
+		t.Fatal("input rows must not be modified")
+	}
+}
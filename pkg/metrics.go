@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics register with the default Prometheus registry, which the SDK serves on
+// Grafana's plugin metrics endpoint.
+const metricsNamespace = "orcascan"
+
+var (
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests sent to the Orca Scan API by endpoint and response status.",
+	}, []string{"endpoint", "status"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Time until the Orca Scan API responded, by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups by cache (fields, sheets, media) and result (hit, miss).",
+	}, []string{"cache", "result"})
+
+	queryRows = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "query_rows",
+		Help:      "Rows per query, fetched from Orca Scan and returned to Grafana.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"stage"})
+
+	normalizationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "normalization_failures_total",
+		Help:      "Cells nulled because they did not fit their column's detected kind.",
+	}, []string{"kind"})
+)
+
+// upstreamEndpoint names the API endpoint path calls, leaving sheet IDs out of the label.
+func upstreamEndpoint(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return parts[0]
+	case len(parts) == 3 && parts[0] == "sheets":
+		return parts[2]
+	}
+	return "other"
+}
+
+// observeUpstream records a request to endpoint that started at start. A zero status
+// means no response arrived.
+func observeUpstream(endpoint string, start time.Time, status int) {
+	label := "error"
+	if status > 0 {
+		label = strconv.Itoa(status)
+	}
+	upstreamRequests.WithLabelValues(endpoint, label).Inc()
+	upstreamDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+}
+
+func observeCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+func observeRows(stage string, count int) {
+	queryRows.WithLabelValues(stage).Observe(float64(count))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUpstreamEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/sheets":            "sheets",
+		"/sheets/abc/rows":   "rows",
+		"/sheets/abc/fields": "fields",
+		"/":                  "other",
+		"/sheets/abc":        "other",
+	}
+	for path, want := range cases {
+		if got := upstreamEndpoint(path); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestUpstreamAndCacheMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sheets/missing/fields" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"key":"name","label":"Name"}]}`))
+	}))
+	defer srv.Close()
+
+	inst := &orcaInstance{baseURL: srv.URL, apiKey: "secret", httpClient: srv.Client(), fieldCache: make(map[string]fieldCacheEntry)}
+	ctx := context.Background()
+
+	ok := upstreamRequests.WithLabelValues("fields", "200")
+	notFound := upstreamRequests.WithLabelValues("fields", "404")
+	hits := cacheLookups.WithLabelValues("fields", "hit")
+	misses := cacheLookups.WithLabelValues("fields", "miss")
+	okBefore, notFoundBefore := testutil.ToFloat64(ok), testutil.ToFloat64(notFound)
+	hitsBefore, missesBefore := testutil.ToFloat64(hits), testutil.ToFloat64(misses)
+
+	for n := 0; n < 2; n++ {
+		if _, err := inst.getFields(ctx, "abc"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := inst.getFields(ctx, "missing"); err == nil {
+		t.Fatal("expected an error for the missing sheet")
+	}
+
+	if got := testutil.ToFloat64(ok) - okBefore; got != 1 {
+		t.Fatalf("expected one successful fields request, got %v", got)
+	}
+	if got := testutil.ToFloat64(notFound) - notFoundBefore; got != 1 {
+		t.Fatalf("expected one failed fields request, got %v", got)
+	}
+	if got := testutil.ToFloat64(hits) - hitsBefore; got != 1 {
+		t.Fatalf("expected one cache hit, got %v", got)
+	}
+	if got := testutil.ToFloat64(misses) - missesBefore; got != 2 {
+		t.Fatalf("expected two cache misses, got %v", got)
+	}
+}
+
+func TestNormalizationFailureMetric(t *testing.T) {
+	failures := normalizationFailures.WithLabelValues(kindName(fieldKindNumber))
+	before := testutil.ToFloat64(failures)
+
+	rows := []map[string]any{{"qty": 1.0}, {"qty": "lots"}, {"qty": nil}}
+	dropOffenders(rows, []fieldDescriptor{{meta: orcaField{Key: "qty"}, kind: fieldKindNumber}})
+
+	if got := testutil.ToFloat64(failures) - before; got != 1 {
+		t.Fatalf("expected one number failure, got %v", got)
+	}
+}
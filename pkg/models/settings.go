@@ -1,8 +1,29 @@
 package models
 
+import (
+	"encoding/json"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
 type Settings struct {
-	BaseURL string `json:"baseUrl"`
-	APIKey  string `json:"apiKey"` // read from secure json data
+	BaseURL   string `json:"baseUrl"`
+	APIKey    string `json:"apiKey"`    // read from secure json data
+	Timezone  string `json:"timezone"`  // IANA zone for date values without an offset
+	DateOrder string `json:"dateOrder"` // DMY, MDY, YMD or auto (default)
+	// DecimalSeparator is "." or "," for sheets typed in that locale; empty infers it per value.
+	DecimalSeparator string `json:"decimalSeparator"`
+	// KeepBarcodesAsText stops columns named like barcodes (SKU, EAN, UPC, GTIN...) being
+	// read as numbers so leading zeros survive; nil means true.
+	KeepBarcodesAsText *bool `json:"keepBarcodesAsText,omitempty"`
+	// DetectionSampleSize bounds how many values per column type detection reads
+	// (default 500; negative reads all). DetectionThreshold is the share of them that
+	// must parse as a type for the column to get it (default 0.98); the rest become null.
+	DetectionSampleSize int     `json:"detectionSampleSize,omitempty"`
+	DetectionThreshold  float64 `json:"detectionThreshold,omitempty"`
+	// IncludeSystemColumns returns Orca's row metadata (_id, created, updated, user) as
+	// columns. Created or updated can drive the time range either way.
+	IncludeSystemColumns bool `json:"includeSystemColumns,omitempty"`
 }
 
 type QueryRange struct {
@@ -13,10 +34,151 @@ type QueryRange struct {
 type OrcaQuery struct {
 	RefID     string     `json:"refId"`
 	SheetID   string     `json:"sheetId"`
+	SheetName string     `json:"sheetName,omitempty"` // exact, case-insensitive or glob; used when SheetID is empty
 	Limit     int        `json:"limit"`
 	Skip      int        `json:"skip"`
 	TimeField string     `json:"timeField"`
 	Range     QueryRange `json:"range"`
+
+	// Mode is "rows" (default), "quality", which returns a per-column data-quality report
+	// instead of rows, "stats", which summarizes the filtered rows as set out in Stats, or
+	// "geohash", which counts the filtered rows per geohash cell as set out in Geohash.
+	Mode    string        `json:"mode,omitempty"`
+	Stats   *QueryStats   `json:"stats,omitempty"`
+	Geohash *QueryGeohash `json:"geohash,omitempty"`
+
+	// Format is "rows" (default) or "geojson", which returns the rows as a GeoJSON
+	// FeatureCollection of points located by GeoField (default: the first geo column).
+	Format   string `json:"format,omitempty"`
+	GeoField string `json:"geoField,omitempty"`
+
+	// Timezone interprets zone-less date values; it falls back to the datasource setting,
+	// then DashboardTimezone (sent by the frontend), then UTC.
+	Timezone          string `json:"timezone,omitempty"`
+	DashboardTimezone string `json:"dashboardTimezone,omitempty"`
+
+	// FieldOptions holds per-field parsing overrides keyed by field key or label.
+	FieldOptions map[string]QueryFieldOptions `json:"fieldOptions,omitempty"`
+	// FieldTypes forces the type of a field, keyed like FieldOptions: string, number,
+	// boolean, time, geo, enum, json or barcode. It takes precedence over detection.
+	FieldTypes map[string]string `json:"fieldTypes,omitempty"`
+	// SystemColumns overrides the datasource's IncludeSystemColumns for this query.
+	SystemColumns *bool `json:"systemColumns,omitempty"`
+
+	Join *QueryJoin `json:"join,omitempty"`
+
+	// Spatial keeps rows whose geo column lies inside an area, after the time filter.
+	Spatial *QuerySpatial `json:"spatial,omitempty"`
+
+	// SheetIDs and SheetPattern select extra sheets whose rows are concatenated with
+	// SheetID's; SourceField names the column that records each row's sheet.
+	SheetIDs     []string `json:"sheetIds,omitempty"`
+	SheetPattern string   `json:"sheetPattern,omitempty"`
+	SourceField  string   `json:"sourceField,omitempty"`
+
+	// Flatten rewrites array and object cells before computed columns are evaluated.
+	Flatten  []QueryFlatten   `json:"flatten,omitempty"`
+	Computed []ComputedColumn `json:"computed,omitempty"`
+
+	// Sort, TopN and Columns shape the filtered rows before they are returned.
+	Sort    []QuerySort   `json:"sort,omitempty"`
+	TopN    int           `json:"topN,omitempty"`
+	Columns []QueryColumn `json:"columns,omitempty"`
+}
+
+type QueryFieldOptions struct {
+	DateOrder string `json:"dateOrder,omitempty"` // DMY, MDY, YMD or auto
+	// Layouts are tried before the built-in time layouts, e.g. "DD.MM.YYYY HH:mm";
+	// "epoch", "epoch_ms" and "excel" choose how numeric values are read.
+	Layouts []string `json:"layouts,omitempty"`
+
+	DecimalSeparator string `json:"decimalSeparator,omitempty"` // "." or ","
+
+	// Display overrides emitted in the field config.
+	DisplayName string   `json:"displayName,omitempty"`
+	Unit        string   `json:"unit,omitempty"` // Grafana unit id, e.g. currencyEUR or masskg
+	Decimals    *int     `json:"decimals,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+}
+
+// QueryStats selects the columns a stats query summarizes (all number and text columns
+// when empty) and shapes its histograms and top-K lists.
+type QueryStats struct {
+	Fields      []string `json:"fields,omitempty"`
+	Buckets     int      `json:"buckets,omitempty"`     // histogram bucket count, default 10
+	BucketWidth float64  `json:"bucketWidth,omitempty"` // fixed bucket width; overrides Buckets
+	TopK        int      `json:"topK,omitempty"`        // most frequent text values, default 10
+}
+
+// QueryGeohash bins the coordinates in Field (default: the first geo column) into
+// geohash cells of Precision characters (default 6) and aggregates the number column
+// Value per cell with Aggregates: sum, avg, min or max (default avg).
+type QueryGeohash struct {
+	Field      string   `json:"field,omitempty"`
+	Precision  int      `json:"precision,omitempty"`
+	Value      string   `json:"value,omitempty"`
+	Aggregates []string `json:"aggregates,omitempty"`
+}
+
+type QuerySort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// QueryColumn selects a column for the response, optionally renamed to Alias.
+type QueryColumn struct {
+	Field string `json:"field"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// ComputedColumn adds a column whose value is an expression over the normalized row,
+// e.g. "Quantity * [Unit Price]".
+type ComputedColumn struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Decimals   *int   `json:"decimals,omitempty"` // rounds results; inferred from the expression when nil
+}
+
+// QueryFlatten turns a multi-value column into plain values. Strategy is "join" (the
+// elements as text, joined by Separator, default ", "), "explode" (one row per element),
+// "path" (the value at a JSON path such as "photos[0].url") or "count" (the number of
+// elements). The result replaces Field, or is added after it as Alias.
+type QueryFlatten struct {
+	Field     string `json:"field"`
+	Strategy  string `json:"strategy"`
+	Separator string `json:"separator,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Alias     string `json:"alias,omitempty"`
+}
+
+// QuerySpatial filters rows by the coordinates in Field (default: the first geo column).
+// Rows must lie within every area set. DistanceField adds a column with each row's
+// distance in metres from the Radius centre; a zero Radius.Meters only adds the column.
+type QuerySpatial struct {
+	Field  string       `json:"field,omitempty"`
+	Radius *QueryRadius `json:"radius,omitempty"`
+	BBox   []float64    `json:"bbox,omitempty"` // [minLon, minLat, maxLon, maxLat]
+	// Polygon is a GeoJSON Polygon or MultiPolygon, bare or wrapped in a Feature or
+	// FeatureCollection.
+	Polygon       json.RawMessage `json:"polygon,omitempty"`
+	DistanceField string          `json:"distanceField,omitempty"`
+}
+
+type QueryRadius struct {
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+	Meters float64 `json:"meters"`
+}
+
+// QueryJoin joins the rows of a secondary sheet onto the query sheet.
+type QueryJoin struct {
+	SheetID   string `json:"sheetId"`
+	SheetName string `json:"sheetName,omitempty"`
+	LeftKey   string `json:"leftKey"`
+	RightKey  string `json:"rightKey"`
+	Type      string `json:"type"`   // "inner" (default) or "left"
+	Prefix    string `json:"prefix"` // applied to secondary columns that collide with primary ones
 }
 
 type Field struct {
@@ -27,4 +189,14 @@ type Field struct {
 	GrafanaType string `json:"grafanaType"`
 	IsTime      bool   `json:"isTime,omitempty"`
 	Decimals    *int   `json:"decimals,omitempty"`
+
+	DateOrder          string `json:"dateOrder,omitempty"`
+	DateOrderAmbiguous bool   `json:"dateOrderAmbiguous,omitempty"`
+
+	Config *data.FieldConfig `json:"config,omitempty"`
+
+	// Confidence is the share of sampled values that fit the detected type; Offenders
+	// counts values that did not parse as it and were returned as null.
+	Confidence *float64 `json:"confidence,omitempty"`
+	Offenders  int      `json:"offenders,omitempty"`
 }
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+const (
+	flattenJoin    = "join"
+	flattenExplode = "explode"
+	flattenPath    = "path"
+	flattenCount   = "count"
+
+	defaultFlattenSeparator = ", "
+	maxExplodedRows         = 100000
+)
+
+// urlKeys are the object keys attachments, photos and signatures carry their address in.
+var urlKeys = []string{"url", "href", "src", "link", "downloadUrl", "fileUrl"}
+
+// textKeys are tried, in order, to give an object element a readable text when joined.
+var textKeys = []string{"label", "name", "title", "text", "value", "fileName"}
+
+// isNestedValue reports whether val is an array or object cell.
+func isNestedValue(val any) bool {
+	switch normalizeJSON(val).(type) {
+	case []any, map[string]any:
+		return true
+	}
+	return false
+}
+
+// cellElements returns the elements of a multi-value cell: an array's items, or the
+// cell itself when it holds a single value. Empty cells have none.
+func cellElements(val any) []any {
+	switch v := normalizeJSON(val).(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		return []any{v}
+	default:
+		return []any{v}
+	}
+}
+
+// elementText renders an element for a joined cell; objects use their first readable
+// key, or else their JSON.
+func elementText(elem any) string {
+	if obj, ok := elem.(map[string]any); ok {
+		for _, name := range textKeys {
+			if s, ok := stringifyValue(objectValue(obj, name)).(string); ok && s != "" {
+				return s
+			}
+		}
+		raw, _ := json.Marshal(obj)
+		return string(raw)
+	}
+	if arr, ok := elem.([]any); ok {
+		raw, _ := json.Marshal(arr)
+		return string(raw)
+	}
+	s, _ := stringifyValue(elem).(string)
+	return s
+}
+
+// objectValue returns obj[name], matching the key case-insensitively when there is no
+// exact match.
+func objectValue(obj map[string]any, name string) any {
+	if val, ok := obj[name]; ok {
+		return val
+	}
+	for key, val := range obj {
+		if strings.EqualFold(key, name) {
+			return val
+		}
+	}
+	return nil
+}
+
+// linkURL returns the web address a cell or element points to: an http(s) string or an
+// object carrying one under a url-like key.
+func linkURL(val any) (string, bool) {
+	switch v := normalizeJSON(val).(type) {
+	case string:
+		s := strings.TrimSpace(v)
+		lower := strings.ToLower(s)
+		if strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") {
+			return s, !strings.ContainsAny(s, " \t\n")
+		}
+	case map[string]any:
+		for _, name := range urlKeys {
+			if url, ok := linkURL(objectValue(v, name)); ok {
+				return url, true
+			}
+		}
+	}
+	return "", false
+}
+
+// jsonPathStep is one key or index of a parsed JSON path; wildcard maps over an array.
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath reads paths such as "$.photos[0].url", "items[*].name" or "a.b".
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	var steps []jsonPathStep
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, newRequestError("unclosed [ in JSON path")
+			}
+			inner := strings.Trim(strings.TrimSpace(path[1:end]), `"'`)
+			path = path[end+1:]
+			if inner == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+				continue
+			}
+			if idx, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, jsonPathStep{index: idx, isIndex: true})
+				continue
+			}
+			if inner == "" {
+				return nil, newRequestError("empty [] in JSON path")
+			}
+			steps = append(steps, jsonPathStep{key: inner})
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			key := path[:end]
+			path = path[end:]
+			if key == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: key})
+			}
+		}
+	}
+	if len(steps) == 0 {
+		return nil, newRequestError("JSON path is empty")
+	}
+	return steps, nil
+}
+
+// extractJSONPath follows steps through val; a missing key or index yields nil and a
+// wildcard yields the array of its matches.
+func extractJSONPath(val any, steps []jsonPathStep) any {
+	val = normalizeJSON(val)
+	if len(steps) == 0 || val == nil {
+		return val
+	}
+
+	step, rest := steps[0], steps[1:]
+	switch {
+	case step.wildcard:
+		var items []any
+		switch v := val.(type) {
+		case []any:
+			items = v
+		case map[string]any:
+			for _, key := range sortedKeys(v) {
+				items = append(items, v[key])
+			}
+		default:
+			return nil
+		}
+		out := make([]any, 0, len(items))
+		for _, item := range items {
+			if found := extractJSONPath(item, rest); found != nil {
+				out = append(out, found)
+			}
+		}
+		return out
+	case step.isIndex:
+		arr, ok := val.([]any)
+		if !ok {
+			return nil
+		}
+		idx := step.index
+		if idx < 0 {
+			idx += len(arr)
+		}
+		if idx < 0 || idx >= len(arr) {
+			return nil
+		}
+		return extractJSONPath(arr[idx], rest)
+	default:
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return nil
+		}
+		return extractJSONPath(objectValue(obj, step.key), rest)
+	}
+}
+
+func sortedKeys(obj map[string]any) []string {
+	seen := make(map[string]struct{}, len(obj))
+	for key := range obj {
+		seen[key] = struct{}{}
+	}
+	return sortedEnumValues(seen)
+}
+
+// applyFlatten rewrites multi-value columns as set out in specs, in order: join the
+// elements into text, explode into one row per element, extract a JSON path or count
+// the elements. Each result replaces its column, or is added after it under Alias.
+func applyFlatten(sheet sheetData, specs []models.QueryFlatten) (sheetData, error) {
+	if len(specs) == 0 {
+		return sheet, nil
+	}
+
+	descList := append([]fieldDescriptor(nil), sheet.descList...)
+	rows := copyRows(sheet.rows)
+
+	for _, spec := range specs {
+		key, ok := resolveFieldKey(normalizeFieldKey(spec.Field), descList, rows)
+		if !ok {
+			return sheetData{}, newRequestError("flatten field %q not found", spec.Field)
+		}
+		target := key
+		if alias := normalizeFieldKey(spec.Alias); alias != "" && alias != key {
+			for _, desc := range descList {
+				if desc.meta.Key == alias {
+					return sheetData{}, newRequestError("flatten alias %q already exists", alias)
+				}
+			}
+			target = alias
+		}
+
+		source := fieldDescriptor{meta: orcaField{Key: key, Label: key}}
+		sourceIdx := -1
+		for idx, desc := range descList {
+			if desc.meta.Key == key {
+				source, sourceIdx = desc, idx
+				break
+			}
+		}
+
+		var kind fieldKind
+		switch strings.ToLower(strings.TrimSpace(spec.Strategy)) {
+		case flattenJoin:
+			sep := spec.Separator
+			if sep == "" {
+				sep = defaultFlattenSeparator
+			}
+			for _, row := range rows {
+				parts := make([]string, 0)
+				for _, elem := range cellElements(row[key]) {
+					if text := elementText(elem); text != "" {
+						parts = append(parts, text)
+					}
+				}
+				if len(parts) == 0 {
+					row[target] = nil
+				} else {
+					row[target] = strings.Join(parts, sep)
+				}
+			}
+			kind = fieldKindString
+		case flattenCount:
+			for _, row := range rows {
+				row[target] = float64(len(cellElements(row[key])))
+			}
+			kind = fieldKindNumber
+		case flattenPath:
+			steps, err := parseJSONPath(spec.Path)
+			if err != nil {
+				return sheetData{}, newRequestError("flatten field %q: %v", key, err)
+			}
+			for _, row := range rows {
+				row[target] = extractJSONPath(row[key], steps)
+			}
+			kind = detectFlattenedKind(target, rows, source.opts)
+		case flattenExplode:
+			exploded := make([]map[string]any, 0, len(rows))
+			for _, row := range rows {
+				elems := cellElements(row[key])
+				if len(elems) == 0 {
+					row[target] = nil
+					exploded = append(exploded, row)
+					continue
+				}
+				for _, elem := range elems {
+					out := make(map[string]any, len(row)+1)
+					for k, v := range row {
+						out[k] = v
+					}
+					out[target] = elem
+					exploded = append(exploded, out)
+				}
+				if len(exploded) > maxExplodedRows {
+					return sheetData{}, newRequestError("exploding %q returns more than %d rows", key, maxExplodedRows)
+				}
+			}
+			rows = exploded
+			kind = detectFlattenedKind(target, rows, source.opts)
+		default:
+			return sheetData{}, newRequestError("unknown flatten strategy %q (want join, explode, path or count)", spec.Strategy)
+		}
+
+		if kind != fieldKindString && kind != fieldKindJSON {
+			for _, row := range rows {
+				row[target] = normalizeValue(row[target], kind, source.opts)
+			}
+		}
+
+		desc := fieldDescriptor{
+			meta: orcaField{Key: target, Label: target},
+			kind: kind,
+			opts: source.opts,
+		}
+		if target == key {
+			desc.meta = source.meta
+		}
+		if kind == fieldKindNumber {
+			if decimals, ok, _ := numberColumnInfo(target, rows, source.opts); ok && decimals > 0 {
+				desc.decimals, desc.hasDecimals = decimals, true
+			}
+		}
+
+		switch {
+		case target == key && sourceIdx >= 0:
+			descList[sourceIdx] = desc
+		case sourceIdx >= 0:
+			descList = append(descList[:sourceIdx+1], append([]fieldDescriptor{desc}, descList[sourceIdx+1:]...)...)
+		default:
+			descList = append(descList, desc)
+		}
+	}
+
+	descMap := make(map[string]fieldDescriptor, len(descList))
+	for _, desc := range descList {
+		descMap[desc.meta.Key] = desc
+	}
+	return sheetData{sheetID: sheet.sheetID, rows: rows, descList: descList, descMap: descMap, columns: appendDescriptorColumns(sheet.columns, descList)}, nil
+}
+
+// detectFlattenedKind types a column produced by flattening: nested values stay JSON,
+// anything else is detected like a sheet column.
+func detectFlattenedKind(key string, rows []map[string]any, opts parseOptions) fieldKind {
+	for _, row := range rows {
+		if isNestedValue(row[key]) {
+			return fieldKindJSON
+		}
+	}
+	return detectKindFromRowsWithOptions(key, rows, fieldKindString, opts)
+}
+
+// addLinkColumns marks text columns whose values are web addresses as links, and adds a
+// <key>_url link column holding the first address in each multi-value cell of JSON
+// columns such as attachments, photos and signatures. Explode those columns first to
+// link every element.
+func addLinkColumns(sheet sheetData) sheetData {
+	descList := make([]fieldDescriptor, 0, len(sheet.descList))
+	var added bool
+	rows := sheet.rows
+
+	for _, desc := range sheet.descList {
+		key := desc.meta.Key
+		switch desc.kind {
+		case fieldKindString:
+			if isLinkColumn(key, rows) {
+				desc.link = true
+			}
+			descList = append(descList, desc)
+		case fieldKindJSON:
+			descList = append(descList, desc)
+
+			urlKey := key + "_url"
+			if _, exists := sheet.descMap[urlKey]; exists {
+				continue
+			}
+			urls := make([]any, len(rows))
+			found := false
+			for idx, row := range rows {
+				for _, elem := range cellElements(row[key]) {
+					if url, ok := linkURL(elem); ok {
+						urls[idx] = url
+						found = true
+						break
+					}
+				}
+			}
+			if !found {
+				continue
+			}
+			if !added {
+				rows = copyRows(rows)
+				added = true
+			}
+			for idx, row := range rows {
+				row[urlKey] = urls[idx]
+			}
+			descList = append(descList, fieldDescriptor{
+				meta: orcaField{Key: urlKey, Label: labelOrKey(desc.meta) + " URL"},
+				kind: fieldKindString,
+				opts: desc.opts,
+				link: true,
+			})
+		default:
+			descList = append(descList, desc)
+		}
+	}
+
+	descMap := make(map[string]fieldDescriptor, len(descList))
+	for _, desc := range descList {
+		descMap[desc.meta.Key] = desc
+	}
+	return sheetData{sheetID: sheet.sheetID, rows: rows, descList: descList, descMap: descMap, columns: appendDescriptorColumns(sheet.columns, descList)}
+}
+
+// isLinkColumn reports whether every non-empty value of key is a web address.
+func isLinkColumn(key string, rows []map[string]any) bool {
+	seen := false
+	for _, row := range rows {
+		val := row[key]
+		if isEmptyCell(val) {
+			continue
+		}
+		if _, ok := val.(string); !ok {
+			return false
+		}
+		if _, ok := linkURL(val); !ok {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+func copyRows(rows []map[string]any) []map[string]any {
+	out := make([]map[string]any, len(rows))
+	for idx, row := range rows {
+		cp := make(map[string]any, len(row)+1)
+		for key, val := range row {
+			cp[key] = val
+		}
+		out[idx] = cp
+	}
+	return out
+}
+
+// linkConfig opens a link column's value in a new tab.
+func linkConfig() []data.DataLink {
+	return []data.DataLink{{Title: "Open", URL: "${__value.raw}", TargetBlank: true}}
+}
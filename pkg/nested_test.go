@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func nestedSheet() sheetData {
+	rows := []map[string]any{
+		{"Name": "box", "Tags": []any{"red", "large"}, "Photos": []any{
+			map[string]any{"url": "https://cdn.example.com/a.jpg", "name": "front"},
+			map[string]any{"url": "https://cdn.example.com/b.jpg", "name": "back"},
+		}},
+		{"Name": "bag", "Tags": `["blue"]`, "Photos": nil},
+		{"Name": "tin", "Tags": nil, "Photos": []any{}},
+	}
+	descList := []fieldDescriptor{
+		{meta: orcaField{Key: "Name"}, kind: fieldKindString},
+		{meta: orcaField{Key: "Tags"}, kind: fieldKindJSON},
+		{meta: orcaField{Key: "Photos"}, kind: fieldKindJSON},
+	}
+	descMap := make(map[string]fieldDescriptor)
+	for _, desc := range descList {
+		descMap[desc.meta.Key] = desc
+	}
+	return sheetData{rows: rows, descList: descList, descMap: descMap}
+}
+
+func TestDetectKindNested(t *testing.T) {
+	rows := []map[string]any{{"Tags": []any{"a"}}, {"Tags": map[string]any{"x": 1.0}}, {"Tags": `["b"]`}}
+	if kind := detectKind("Tags", rows, fieldKindString, parseOptions{}).kind; kind != fieldKindJSON {
+		t.Fatalf("expected json, got %s", kindName(kind))
+	}
+}
+
+func TestApplyFlattenStrategies(t *testing.T) {
+	sheet := nestedSheet()
+	out, err := applyFlatten(sheet, []models.QueryFlatten{
+		{Field: "tags", Strategy: "count", Alias: "Tag Count"},
+		{Field: "Tags", Strategy: "join", Separator: " | "},
+		{Field: "Photos", Strategy: "path", Path: "$[*].name", Alias: "Photo Names"},
+	})
+	if err != nil {
+		t.Fatalf("applyFlatten: %v", err)
+	}
+
+	if out.rows[0]["Tags"] != "red | large" || out.rows[1]["Tags"] != "blue" || out.rows[2]["Tags"] != nil {
+		t.Fatalf("unexpected joined tags %v", out.rows)
+	}
+	if out.rows[0]["Tag Count"] != 2.0 || out.rows[2]["Tag Count"] != 0.0 {
+		t.Fatalf("unexpected counts %v", out.rows)
+	}
+	if !reflect.DeepEqual(out.rows[0]["Photo Names"], []any{"front", "back"}) {
+		t.Fatalf("unexpected path result %v", out.rows[0]["Photo Names"])
+	}
+
+	keys := make([]string, 0, len(out.descList))
+	for _, desc := range out.descList {
+		keys = append(keys, desc.meta.Key)
+	}
+	if want := []string{"Name", "Tags", "Tag Count", "Photos", "Photo Names"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("got columns %v, want %v", keys, want)
+	}
+	if out.descMap["Tags"].kind != fieldKindString || out.descMap["Tag Count"].kind != fieldKindNumber || out.descMap["Photo Names"].kind != fieldKindJSON {
+		t.Fatalf("unexpected kinds %+v", out.descList)
+	}
+	if _, ok := sheet.rows[0]["Tag Count"]; ok {
+		t.Fatal("input rows must not be modified")
+	}
+}
+
+func TestApplyFlattenExplode(t *testing.T) {
+	out, err := applyFlatten(nestedSheet(), []models.QueryFlatten{
+		{Field: "Photos", Strategy: "explode"},
+		{Field: "Photos", Strategy: "path", Path: "url", Alias: "Photo"},
+	})
+	if err != nil {
+		t.Fatalf("applyFlatten: %v", err)
+	}
+	if len(out.rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(out.rows))
+	}
+	if out.rows[0]["Name"] != "box" || out.rows[1]["Photo"] != "https://cdn.example.com/b.jpg" || out.rows[2]["Photo"] != nil {
+		t.Fatalf("unexpected exploded rows %v", out.rows)
+	}
+}
+
+func TestApplyFlattenInvalid(t *testing.T) {
+	specs := [][]models.QueryFlatten{
+		{{Field: "Missing", Strategy: "join"}},
+		{{Field: "Tags", Strategy: "zip"}},
+		{{Field: "Tags", Strategy: "path", Path: "a[0"}},
+		{{Field: "Tags", Strategy: "count", Alias: "Name"}},
+	}
+	for _, spec := range specs {
+		if _, err := applyFlatten(nestedSheet(), spec); statusFromError(err) != 400 {
+			t.Fatalf("expected request error for %+v, got %v", spec, err)
+		}
+	}
+}
+
+func TestExtractJSONPath(t *testing.T) {
+	val := map[string]any{"items": []any{map[string]any{"Sku": "a"}, map[string]any{"sku": "b"}}}
+	cases := map[string]any{
+		"items[0].sku":    "a",
+		"$.items[-1].sku": "b",
+		"items[5].sku":    nil,
+		"items[*].sku":    []any{"a", "b"},
+		"missing.key":     nil,
+	}
+	for path, want := range cases {
+		steps, err := parseJSONPath(path)
+		if err != nil {
+			t.Fatalf("parse %q: %v", path, err)
+		}
+		if got := extractJSONPath(val, steps); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestAddLinkColumns(t *testing.T) {
+	sheet := nestedSheet()
+	sheet.rows[0]["Site"] = "https://example.com"
+	sheet.rows[1]["Site"] = "http://example.org/x"
+	sheet.descList = append(sheet.descList, fieldDescriptor{meta: orcaField{Key: "Site"}, kind: fieldKindString})
+	sheet.descMap["Site"] = sheet.descList[len(sheet.descList)-1]
+
+	out := addLinkColumns(sheet)
+	if !out.descMap["Site"].link || out.descMap["Name"].link {
+		t.Fatalf("unexpected link flags %+v", out.descList)
+	}
+	urlDesc, ok := out.descMap["Photos_url"]
+	if !ok || !urlDesc.link || urlDesc.meta.Label != "Photos URL" {
+		t.Fatalf("expected a Photos_url link column, got %+v", out.descList)
+	}
+	if _, ok := out.descMap["Tags_url"]; ok {
+		t.Fatal("did not expect a url column for tags")
+	}
+	if out.rows[0]["Photos_url"] != "https://cdn.example.com/a.jpg" || out.rows[1]["Photos_url"] != nil {
+		t.Fatalf("unexpected url values %v", out.rows)
+	}
+	if _, ok := sheet.rows[0]["Photos_url"]; ok {
+		t.Fatal("input rows must not be modified")
+	}
+
+	if cfg := fieldConfig(urlDesc); cfg == nil || len(cfg.Links) != 1 || cfg.Links[0].URL != "${__value.raw}" {
+		t.Fatalf("unexpected link config %+v", cfg)
+	}
+}
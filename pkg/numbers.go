@@ -0,0 +1,297 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parsedNumber is a numeric cell value together with what its text told us about it.
+type parsedNumber struct {
+	value    float64
+	decimals int
+	unit     string // Grafana unit implied by a currency symbol, percent sign or unit suffix
+}
+
+// currencyUnits maps currency symbols and ISO codes to Grafana unit ids.
+var currencyUnits = map[string]string{
+	"$":   "currencyUSD",
+	"US$": "currencyUSD",
+	"USD": "currencyUSD",
+	"€":   "currencyEUR",
+	"EUR": "currencyEUR",
+	"£":   "currencyGBP",
+	"GBP": "currencyGBP",
+	"¥":   "currencyJPY",
+	"JPY": "currencyJPY",
+	"₹":   "currencyINR",
+	"INR": "currencyINR",
+	"₩":   "currencyKRW",
+	"KRW": "currencyKRW",
+	"₽":   "currencyRUB",
+	"RUB": "currencyRUB",
+	"CHF": "currencyCHF",
+	"SEK": "currencySEK",
+	"NOK": "currencyNOK",
+	"DKK": "currencyDKK",
+	"PLN": "currencyPLN",
+	"zł":  "currencyPLN",
+	"CZK": "currencyCZK",
+	"Kč":  "currencyCZK",
+	"R$":  "currencyBRL",
+	"BRL": "currencyBRL",
+	"ZAR": "currencyZAR",
+}
+
+// currencyTokens lists the keys of currencyUnits longest first so "US$" wins over "$".
+var currencyTokens = []string{"US$", "R$", "USD", "EUR", "GBP", "JPY", "INR", "KRW", "RUB", "CHF", "SEK", "NOK", "DKK", "PLN", "CZK", "BRL", "ZAR", "zł", "Kč", "$", "€", "£", "¥", "₹", "₩", "₽"}
+
+func parseDecimalSeparator(v string) (rune, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "auto":
+		return 0, nil
+	case ".", "dot", "period":
+		return '.', nil
+	case ",", "comma":
+		return ',', nil
+	default:
+		return 0, newRequestError("unknown decimal separator %q (expected \".\", \",\" or auto)", v)
+	}
+}
+
+// parseNumberString parses numbers as people type them into sheets: "1,234.5",
+// "1.234,56", "1 234,5", "€12.50", "12.5%", "(42)". decimalSep is '.' or ',', or 0 to
+// infer it from the value: the last of mixed separators is the decimal one, a lone dot is
+// a decimal point and a lone comma is grouping only when three digits follow it ("1,234").
+func parseNumberString(s string, decimalSep rune) (parsedNumber, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return parsedNumber{}, false
+	}
+
+	if strings.ContainsAny(s, "eE") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return parsedNumber{value: f}, true
+		}
+	}
+
+	var result parsedNumber
+	negative := false
+
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	s, sign := stripSign(s)
+	if sign < 0 {
+		negative = !negative
+	}
+
+	if strings.HasSuffix(s, "%") {
+		result.unit = "percent"
+		s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	} else if strings.HasPrefix(s, "%") {
+		result.unit = "percent"
+		s = strings.TrimSpace(strings.TrimPrefix(s, "%"))
+	} else if rest, unit, ok := stripCurrency(s); ok {
+		result.unit = unit
+		s = rest
+	} else if rest, unit, ok := stripMeasurementUnit(s); ok {
+		result.unit = unit
+		s = rest
+	}
+
+	if result.unit != "" {
+		var sign int
+		s, sign = stripSign(s)
+		if sign < 0 {
+			negative = !negative
+		}
+	}
+
+	digits, decimals, ok := normalizeDigits(s, decimalSep)
+	if !ok {
+		return parsedNumber{}, false
+	}
+
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return parsedNumber{}, false
+	}
+	if negative {
+		f = -f
+	}
+
+	result.value = f
+	result.decimals = decimals
+	return result, true
+}
+
+func stripSign(s string) (string, int) {
+	switch {
+	case strings.HasPrefix(s, "-"), strings.HasPrefix(s, "−"):
+		_, size := firstRune(s)
+		return strings.TrimSpace(s[size:]), -1
+	case strings.HasPrefix(s, "+"):
+		return strings.TrimSpace(s[1:]), 1
+	}
+	return s, 0
+}
+
+func firstRune(s string) (rune, int) {
+	for _, r := range s {
+		return r, len(string(r))
+	}
+	return 0, 0
+}
+
+func stripCurrency(s string) (string, string, bool) {
+	for _, token := range currencyTokens {
+		if strings.HasPrefix(s, token) {
+			return strings.TrimSpace(s[len(token):]), currencyUnits[token], true
+		}
+		if strings.HasSuffix(s, token) {
+			return strings.TrimSpace(s[:len(s)-len(token)]), currencyUnits[token], true
+		}
+	}
+	return s, "", false
+}
+
+// normalizeDigits removes grouping separators and rewrites the decimal separator as '.',
+// returning the digits after it as the decimals count.
+func normalizeDigits(s string, decimalSep rune) (string, int, bool) {
+	if s == "" {
+		return "", 0, false
+	}
+
+	var cleaned strings.Builder
+	cleaned.Grow(len(s))
+	lastDot, lastComma := -1, -1
+	dots, commas := 0, 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			cleaned.WriteRune(r)
+		case r == '.':
+			lastDot = cleaned.Len()
+			dots++
+			cleaned.WriteRune(r)
+		case r == ',':
+			lastComma = cleaned.Len()
+			commas++
+			cleaned.WriteRune(r)
+		case r == '\'' || r == '’' || unicode.IsSpace(r):
+			cleaned.WriteRune('_')
+		default:
+			return "", 0, false
+		}
+	}
+	text := cleaned.String()
+
+	sep := decimalSep
+	if sep == 0 {
+		switch {
+		case dots > 0 && commas > 0:
+			sep = ','
+			if lastDot > lastComma {
+				sep = '.'
+			}
+		case dots == 1:
+			sep = '.'
+		case dots > 1:
+			sep = ','
+		case commas == 1 && !looksGrouped(text, ','):
+			sep = ','
+		default:
+			sep = '.'
+		}
+	}
+
+	group := ','
+	if sep == ',' {
+		group = '.'
+	}
+
+	intPart, fracPart := text, ""
+	if idx := strings.LastIndexByte(text, byte(sep)); idx >= 0 {
+		intPart, fracPart = text[:idx], text[idx+1:]
+	}
+	if strings.ContainsAny(fracPart, ".,_") {
+		return "", 0, false
+	}
+	if strings.ContainsRune(intPart, sep) {
+		return "", 0, false
+	}
+
+	if strings.ContainsAny(intPart, string(group)+"_") {
+		groups := strings.FieldsFunc(intPart, func(r rune) bool { return r == group || r == '_' })
+		if len(groups) < 2 || groups[0] == "" || len(groups[0]) > 3 {
+			return "", 0, false
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return "", 0, false
+			}
+		}
+		if strings.Count(intPart, string(group))+strings.Count(intPart, "_") != len(groups)-1 {
+			return "", 0, false
+		}
+		intPart = strings.Join(groups, "")
+	}
+
+	if intPart == "" && fracPart == "" {
+		return "", 0, false
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if fracPart == "" {
+		return intPart, 0, true
+	}
+	return intPart + "." + fracPart, len(fracPart), true
+}
+
+// looksGrouped reports whether the single separator in s is followed by exactly three
+// digits, as in "1,234", so that it reads as grouping rather than a decimal comma.
+func looksGrouped(s string, sep byte) bool {
+	idx := strings.IndexByte(s, sep)
+	if idx <= 0 || idx > 3 {
+		return false
+	}
+	return len(s)-idx-1 == 3
+}
+
+// numberColumnInfo scans a numeric column for the most decimals any value shows and the
+// unit its values share.
+func numberColumnInfo(key string, rows []map[string]any, opts parseOptions) (decimals int, hasDecimals bool, unit string) {
+	unitSeen := false
+	for _, row := range rows {
+		val, ok := row[key]
+		if !ok || val == nil {
+			continue
+		}
+
+		if s, isString := val.(string); isString {
+			parsed, ok := parseNumberString(s, opts.decimalSeparator)
+			if !ok {
+				continue
+			}
+			switch {
+			case !unitSeen:
+				unit, unitSeen = parsed.unit, true
+			case unit != parsed.unit:
+				unit = ""
+			}
+			if !hasDecimals || parsed.decimals > decimals {
+				decimals, hasDecimals = parsed.decimals, true
+			}
+			continue
+		}
+
+		if d, ok := decimalsFromValueWithOptions(val, opts); ok && (!hasDecimals || d > decimals) {
+			decimals, hasDecimals = d, true
+		}
+	}
+	return decimals, hasDecimals, unit
+}
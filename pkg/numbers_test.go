@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestParseNumberString(t *testing.T) {
+	tests := []struct {
+		input    string
+		sep      rune
+		value    float64
+		decimals int
+		unit     string
+		ok       bool
+	}{
+		{"1,234", 0, 1234, 0, "", true},
+		{"1,234.50", 0, 1234.5, 2, "", true},
+		{"1.234,56", 0, 1234.56, 2, "", true},
+		{"1.234.567", 0, 1234567, 0, "", true},
+		{"12,5", 0, 12.5, 1, "", true},
+		{"1 234,5", 0, 1234.5, 1, "", true},
+		{"1'234.5", 0, 1234.5, 1, "", true},
+		{"€12.50", 0, 12.5, 2, "currencyEUR", true},
+		{"12,50 €", 0, 12.5, 2, "currencyEUR", true},
+		{"-$3", 0, -3, 0, "currencyUSD", true},
+		{"(42.10)", 0, -42.1, 2, "", true},
+		{"12.5%", 0, 12.5, 1, "percent", true},
+		{"1.234", ',', 1234, 0, "", true},
+		{"1,234", ',', 1.234, 3, "", true},
+		{"1e3", 0, 1000, 0, "", true},
+		{"1,2,3", 0, 0, 0, "", false},
+		{"2025-09-01", 0, 0, 0, "", false},
+		{"020 7946 0958", 0, 0, 0, "", false},
+		{"abc", 0, 0, 0, "", false},
+		{"€", 0, 0, 0, "", false},
+	}
+
+	for _, tc := range tests {
+		got, ok := parseNumberString(tc.input, tc.sep)
+		if ok != tc.ok {
+			t.Fatalf("%q: expected ok=%v got %v (%+v)", tc.input, tc.ok, ok, got)
+		}
+		if !ok {
+			continue
+		}
+		if got.value != tc.value || got.decimals != tc.decimals || got.unit != tc.unit {
+			t.Fatalf("%q: expected %v/%d/%q got %+v", tc.input, tc.value, tc.decimals, tc.unit, got)
+		}
+	}
+}
+
+func TestBuildFieldDescriptorsLocaleNumbers(t *testing.T) {
+	fields := []orcaField{{Key: "price"}, {Key: "mixed"}}
+	rows := []map[string]any{
+		{"price": "€1.234,50", "mixed": "$1"},
+		{"price": "€12,00", "mixed": "€2"},
+	}
+
+	_, descMap := buildFieldDescriptors(fields, rows, parseOptions{})
+	price := descMap["price"]
+	if price.kind != fieldKindNumber || price.unit != "currencyEUR" || price.decimals != 2 {
+		t.Fatalf("unexpected price descriptor: %+v", price)
+	}
+	if descMap["mixed"].unit != "" {
+		t.Fatalf("expected conflicting currencies to drop the unit, got %q", descMap["mixed"].unit)
+	}
+
+	normalized := normalizeRows(rows, descMap)
+	if normalized[0]["price"] != 1234.5 {
+		t.Fatalf("expected normalized price 1234.5, got %#v", normalized[0]["price"])
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+// parseOptions controls how raw cell values are interpreted. The zero value parses
+// zone-less times as UTC.
+type parseOptions struct {
+	location    *time.Location
+	dateOrder   dateOrder
+	layouts     []string // tried before the built-in layouts
+	numericTime numericTime
+
+	decimalSeparator rune // '.' or ','; 0 infers it from each value
+
+	// fields holds per-field overrides keyed by the name the query used.
+	fields map[string]models.QueryFieldOptions
+	// types forces the kind of fields, keyed like fields.
+	types map[string]fieldKind
+
+	keepBarcodes  bool // keep barcode-like columns out of numeric detection
+	systemColumns bool // return Orca's row metadata columns
+
+	// sampleSize bounds how many values kind detection reads (0 reads all) and threshold
+	// is the share of them that must fit a kind (0 means all).
+	sampleSize int
+	threshold  float64
+}
+
+func (o parseOptions) loc() *time.Location {
+	if o.location == nil {
+		return time.UTC
+	}
+	return o.location
+}
+
+// queryParseOptions resolves the parse options for a query from the query itself, the
+// datasource settings and the dashboard the query runs in.
+func (i *orcaInstance) queryParseOptions(query models.OrcaQuery) (parseOptions, error) {
+	loc, err := resolveLocation(query.Timezone, i.timezone, query.DashboardTimezone)
+	if err != nil {
+		return parseOptions{}, err
+	}
+
+	order, err := parseDateOrder(i.dateOrder)
+	if err != nil {
+		return parseOptions{}, err
+	}
+
+	decimalSep, err := parseDecimalSeparator(i.decimalSep)
+	if err != nil {
+		return parseOptions{}, err
+	}
+
+	for name, fieldOpts := range query.FieldOptions {
+		if _, err := parseDateOrder(fieldOpts.DateOrder); err != nil {
+			return parseOptions{}, newRequestError("field %q: %v", name, err)
+		}
+		if _, err := parseDecimalSeparator(fieldOpts.DecimalSeparator); err != nil {
+			return parseOptions{}, newRequestError("field %q: %v", name, err)
+		}
+		for _, layout := range fieldOpts.Layouts {
+			if _, _, err := parseTimeLayout(layout); err != nil {
+				return parseOptions{}, newRequestError("field %q: %v", name, err)
+			}
+		}
+	}
+
+	var types map[string]fieldKind
+	for name, raw := range query.FieldTypes {
+		kind, err := parseFieldKind(raw)
+		if err != nil {
+			return parseOptions{}, newRequestError("field %q: %v", name, err)
+		}
+		if types == nil {
+			types = make(map[string]fieldKind, len(query.FieldTypes))
+		}
+		types[name] = kind
+	}
+
+	systemColumns := i.systemColumns
+	if query.SystemColumns != nil {
+		systemColumns = *query.SystemColumns
+	}
+
+	return parseOptions{
+		location:         loc,
+		dateOrder:        order,
+		decimalSeparator: decimalSep,
+		fields:           query.FieldOptions,
+		types:            types,
+		keepBarcodes:     i.keepBarcodes,
+		systemColumns:    systemColumns,
+		sampleSize:       i.sampleSize,
+		threshold:        i.threshold,
+	}, nil
+}
+
+// fieldOptions returns the query overrides for f, matched like a time field by key,
+// label or canonicalized name.
+func (o parseOptions) fieldOptions(f orcaField) (models.QueryFieldOptions, bool) {
+	if fieldOpts, ok := o.fields[f.Key]; ok {
+		return fieldOpts, true
+	}
+	for name, fieldOpts := range o.fields {
+		if fieldNameMatches(name, f) {
+			return fieldOpts, true
+		}
+	}
+	return models.QueryFieldOptions{}, false
+}
+
+// fieldType returns the kind the query forces on f, matched like fieldOptions.
+func (o parseOptions) fieldType(f orcaField) (fieldKind, bool) {
+	if kind, ok := o.types[f.Key]; ok {
+		return kind, true
+	}
+	for name, kind := range o.types {
+		if fieldNameMatches(name, f) {
+			return kind, true
+		}
+	}
+	return fieldKindString, false
+}
+
+func fieldNameMatches(name string, f orcaField) bool {
+	_, ok := resolveFieldKey(normalizeFieldKey(name), []fieldDescriptor{{meta: f}}, nil)
+	return ok
+}
+
+// forField applies the query overrides for f on top of the query-wide options.
+func (o parseOptions) forField(f orcaField) parseOptions {
+	fieldOpts, ok := o.fieldOptions(f)
+	if !ok {
+		return o
+	}
+	if order, err := parseDateOrder(fieldOpts.DateOrder); err == nil && order != dateOrderAuto {
+		o.dateOrder = order
+	}
+	if sep, err := parseDecimalSeparator(fieldOpts.DecimalSeparator); err == nil && sep != 0 {
+		o.decimalSeparator = sep
+	}
+	o.layouts = nil
+	for _, raw := range fieldOpts.Layouts {
+		layout, numeric, err := parseTimeLayout(raw)
+		if err != nil {
+			continue
+		}
+		if numeric != numericTimeAuto {
+			o.numericTime = numeric
+			continue
+		}
+		o.layouts = append(o.layouts, layout)
+	}
+	return o
+}
+
+// resolveLocation loads the first non-empty timezone name in order of precedence.
+// "browser" is skipped because only the frontend knows what it means.
+func resolveLocation(names ...string) (*time.Location, error) {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch strings.ToLower(name) {
+		case "", "browser":
+			continue
+		case "utc":
+			return time.UTC, nil
+		}
+
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, newRequestError("unknown timezone %q", name)
+		}
+		return loc, nil
+	}
+	return time.UTC, nil
+}
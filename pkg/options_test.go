@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveLocation(t *testing.T) {
+	loc, err := resolveLocation("", "browser", "Australia/Sydney")
+	if err != nil || loc.String() != "Australia/Sydney" {
+		t.Fatalf("expected dashboard timezone fallback, got %v (%v)", loc, err)
+	}
+
+	loc, err = resolveLocation("Europe/London", "Australia/Sydney")
+	if err != nil || loc.String() != "Europe/London" {
+		t.Fatalf("expected query timezone to take precedence, got %v (%v)", loc, err)
+	}
+
+	if loc, err := resolveLocation("", ""); err != nil || loc != time.UTC {
+		t.Fatalf("expected UTC default, got %v (%v)", loc, err)
+	}
+
+	if _, err := resolveLocation("Mars/Olympus"); err == nil {
+		t.Fatal("expected error for unknown timezone")
+	}
+}
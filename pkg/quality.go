@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+const (
+	queryModeRows    = "rows"
+	queryModeQuality = "quality"
+
+	qualityInvalidSamples = 5
+)
+
+// qualityFields describes the columns of a quality report, one row per sheet column.
+var qualityFields = []models.Field{
+	{Key: "field", Label: "Field", GrafanaType: "string"},
+	{Key: "label", Label: "Label", GrafanaType: "string"},
+	{Key: "type", Label: "Type", GrafanaType: "string"},
+	{Key: "rows", Label: "Rows", GrafanaType: "number"},
+	{Key: "nulls", Label: "Empty", GrafanaType: "number"},
+	{Key: "nullRate", Label: "Empty rate", GrafanaType: "number", Config: &data.FieldConfig{Unit: "percentunit"}},
+	{Key: "distinct", Label: "Distinct", GrafanaType: "number"},
+	{Key: "duplicates", Label: "Duplicates", GrafanaType: "number"},
+	{Key: "min", Label: "Min", GrafanaType: "string"},
+	{Key: "max", Label: "Max", GrafanaType: "string"},
+	{Key: "invalid", Label: "Invalid", GrafanaType: "number"},
+	{Key: "conformity", Label: "Conformity", GrafanaType: "number", Config: &data.FieldConfig{Unit: "percentunit"}},
+	{Key: "invalidSamples", Label: "Invalid samples", GrafanaType: "string"},
+}
+
+// qualityReport fetches a page of a sheet and reports, per column, how its raw values
+// fared against the detected type: empty cells, distinct and duplicated values, range,
+// and values that did not normalize (bad numbers or dates, out-of-range GPS, barcodes
+// with a bad check digit).
+func (i *orcaInstance) qualityReport(ctx context.Context, sheetID string, limit, skip int, opts parseOptions) ([]map[string]any, error) {
+	rows, columns, err := i.listRows(ctx, sheetID, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldsMeta, err := i.getFields(ctx, sheetID)
+	if err != nil {
+		backend.Logger.Warn("Failed to fetch field metadata", "sheetId", sheetID, "err", err)
+	}
+	if len(fieldsMeta) == 0 {
+		fieldsMeta = fieldsFromColumns(columns)
+	}
+
+	detections := i.cachedDetections(sheetID)
+	descList, _ := buildFieldDescriptorsCached(fieldsMeta, rows, opts, detections)
+	i.storeDetections(sheetID, detections)
+
+	return qualityRows(rows, descList), nil
+}
+
+func qualityRows(rows []map[string]any, descList []fieldDescriptor) []map[string]any {
+	report := make([]map[string]any, 0, len(descList))
+	for _, desc := range descList {
+		report = append(report, columnQuality(desc, rows))
+	}
+	return report
+}
+
+func columnQuality(desc fieldDescriptor, rows []map[string]any) map[string]any {
+	key := desc.meta.Key
+	nulls, invalid := 0, 0
+	counts := make(map[string]int)
+	var invalidSamples []string
+	var minVal, maxVal any
+
+	for _, row := range rows {
+		raw := row[key]
+		if isEmptyCell(raw) {
+			nulls++
+			continue
+		}
+
+		val := normalizeValue(raw, desc.kind, desc.opts)
+		if !valueConforms(raw, val, desc.kind) {
+			invalid++
+			text := fmt.Sprint(stringifyValue(raw))
+			if len(invalidSamples) < qualityInvalidSamples && !containsString(invalidSamples, text) {
+				invalidSamples = append(invalidSamples, text)
+			}
+			continue
+		}
+
+		counts[fmt.Sprint(stringifyValue(val))]++
+		if desc.kind == fieldKindNumber || desc.kind == fieldKindTime {
+			if minVal == nil || compareValues(val, minVal, desc.kind, desc.opts) < 0 {
+				minVal = val
+			}
+			if maxVal == nil || compareValues(val, maxVal, desc.kind, desc.opts) > 0 {
+				maxVal = val
+			}
+		}
+	}
+
+	duplicates := 0
+	for _, n := range counts {
+		if n > 1 {
+			duplicates += n - 1
+		}
+	}
+
+	total := len(rows)
+	nonEmpty := total - nulls
+	out := map[string]any{
+		"field":          key,
+		"label":          labelOrKey(desc.meta),
+		"type":           kindName(desc.kind),
+		"rows":           total,
+		"nulls":          nulls,
+		"nullRate":       nil,
+		"distinct":       len(counts),
+		"duplicates":     duplicates,
+		"min":            qualityValue(minVal),
+		"max":            qualityValue(maxVal),
+		"invalid":        invalid,
+		"conformity":     nil,
+		"invalidSamples": nil,
+	}
+	if total > 0 {
+		out["nullRate"] = float64(nulls) / float64(total)
+	}
+	if nonEmpty > 0 {
+		out["conformity"] = float64(nonEmpty-invalid) / float64(nonEmpty)
+	}
+	if len(invalidSamples) > 0 {
+		out["invalidSamples"] = strings.Join(invalidSamples, ", ")
+	}
+	return out
+}
+
+func isEmptyCell(val any) bool {
+	if val == nil {
+		return true
+	}
+	s, ok := val.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+// valueConforms reports whether raw normalized to a value of its column's kind. Geo
+// values must parse to an in-range coordinate and GS1 barcodes must pass their check digit.
+func valueConforms(raw, normalized any, kind fieldKind) bool {
+	switch kind {
+	case fieldKindGeo:
+		return isGeoValue(raw)
+	case fieldKindBarcode:
+		s, ok := normalized.(string)
+		if !ok {
+			return false
+		}
+		info := parseBarcode(s, time.UTC)
+		return !info.known || info.valid
+	}
+	return conformsToKind(normalized, kind)
+}
+
+func qualityValue(val any) any {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// kindName is the name a kind has in a query's fieldTypes.
+func kindName(kind fieldKind) string {
+	switch kind {
+	case fieldKindNumber:
+		return "number"
+	case fieldKindBoolean:
+		return "boolean"
+	case fieldKindTime:
+		return "time"
+	case fieldKindGeo:
+		return "geo"
+	case fieldKindEnum:
+		return "enum"
+	case fieldKindJSON:
+		return "json"
+	case fieldKindBarcode:
+		return "barcode"
+	default:
+		return "string"
+	}
+}
+
+func (d *orcaDatasource) handleQuality(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	inst, err := d.instanceFromRequest(r)
+	if err != nil {
+		backend.Logger.Error("Quality failed to resolve instance", "err", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := inst.validateAPIKey(); err != nil {
+		backend.Logger.Warn("Quality missing API key")
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.OrcaQuery{
+		SheetID:   strings.TrimSpace(params.Get("sheetId")),
+		SheetName: strings.TrimSpace(params.Get("sheetName")),
+	}
+	query.Limit, _ = strconv.Atoi(params.Get("limit"))
+	query.Skip, _ = strconv.Atoi(params.Get("skip"))
+
+	if query.SheetID == "" && query.SheetName != "" {
+		sheet, err := inst.resolveSheetName(ctx, query.SheetName)
+		if err != nil {
+			writeError(w, statusFromError(err), err)
+			return
+		}
+		query.SheetID = sheet.ID
+	}
+	if query.SheetID == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("sheetId is required"))
+		return
+	}
+
+	opts, err := inst.queryParseOptions(query)
+	if err != nil {
+		writeError(w, statusFromError(err), err)
+		return
+	}
+
+	report, err := inst.qualityReport(ctx, query.SheetID, sanitizeLimit(query.Limit), sanitizeSkip(query.Skip), opts)
+	if err != nil {
+		backend.Logger.Error("Quality report failed", "sheetId", query.SheetID, "err", err)
+		writeError(w, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{
+		"rows":    report,
+		"sheetId": query.SheetID,
+		"fields":  qualityFields,
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestQualityRows(t *testing.T) {
+	rows := []map[string]any{
+		{"Barcode": "4006381333931", "Qty": "3", "Location": "51.5,-0.12"},
+		{"Barcode": "4006381333931", "Qty": "oops", "Location": "95,200"},
+		{"Barcode": "4006381333932", "Qty": "", "Location": nil},
+		{"Barcode": "ABC", "Qty": "10", "Location": "40.7,-74"},
+	}
+	descList := []fieldDescriptor{
+		{meta: orcaField{Key: "Barcode"}, kind: fieldKindBarcode},
+		{meta: orcaField{Key: "Qty", Label: "Quantity"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "Location"}, kind: fieldKindGeo},
+	}
+
+	report := qualityRows(rows, descList)
+	if len(report) != 3 {
+		t.Fatalf("expected one row per column, got %d", len(report))
+	}
+
+	barcode := report[0]
+	if barcode["type"] != "barcode" || barcode["invalid"] != 1 || barcode["duplicates"] != 1 || barcode["distinct"] != 2 {
+		t.Fatalf("unexpected barcode quality %v", barcode)
+	}
+	if barcode["invalidSamples"] != "4006381333932" {
+		t.Fatalf("expected bad check digit sample, got %v", barcode["invalidSamples"])
+	}
+
+	qty := report[1]
+	if qty["label"] != "Quantity" || qty["nulls"] != 1 || qty["nullRate"] != 0.25 || qty["invalid"] != 1 {
+		t.Fatalf("unexpected quantity quality %v", qty)
+	}
+	if qty["min"] != "3" || qty["max"] != "10" || qty["invalidSamples"] != "oops" {
+		t.Fatalf("unexpected quantity range %v", qty)
+	}
+	if qty["conformity"] != 2.0/3.0 {
+		t.Fatalf("expected conformity over non-empty values, got %v", qty["conformity"])
+	}
+
+	location := report[2]
+	if location["invalid"] != 1 || location["invalidSamples"] != "95,200" {
+		t.Fatalf("expected out-of-range GPS to be invalid, got %v", location)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+type sortKey struct {
+	key  string
+	kind fieldKind
+	opts parseOptions
+	desc bool
+}
+
+// sortRows orders rows by the requested keys, comparing values according to the kind of
+// each column. Nulls, then values that do not fit the column's kind, sort last in either
+// direction and ties keep their upstream order.
+// Time values still held as strings are read with the column's options from opts.
+func sortRows(rows []map[string]any, specs []models.QuerySort, descriptors []fieldDescriptor, opts parseOptions) ([]map[string]any, error) {
+	if len(specs) == 0 || len(rows) == 0 {
+		return rows, nil
+	}
+
+	byKey := make(map[string]fieldDescriptor, len(descriptors))
+	for _, desc := range descriptors {
+		byKey[desc.meta.Key] = desc
+	}
+
+	keys := make([]sortKey, 0, len(specs))
+	for _, spec := range specs {
+		input := normalizeFieldKey(spec.Field)
+		key, ok := resolveFieldKey(input, descriptors, rows)
+		if !ok {
+			return nil, newRequestError("sort field %q not found", spec.Field)
+		}
+		sk := sortKey{key: key, opts: opts, desc: spec.Desc}
+		if desc, ok := byKey[key]; ok {
+			sk.kind, sk.opts = desc.kind, opts.forField(desc.meta)
+		} else {
+			sk.kind = detectKindFromRows(key, rows, fieldKindString)
+		}
+		keys = append(keys, sk)
+	}
+
+	sorted := append([]map[string]any(nil), rows...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		for _, k := range keys {
+			av, bv := sorted[a][k.key], sorted[b][k.key]
+			if av == nil || bv == nil {
+				if (av == nil) != (bv == nil) {
+					return bv == nil
+				}
+				continue
+			}
+			if ac, bc := sortConforms(av, k.kind, k.opts), sortConforms(bv, k.kind, k.opts); ac != bc {
+				return ac
+			}
+			cmp := compareValues(av, bv, k.kind, k.opts)
+			if cmp == 0 {
+				continue
+			}
+			if k.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+
+	return sorted, nil
+}
+
+// compareValues compares two non-null values of a column of the given kind. Values that
+// do not conform to the kind sort after those that do and compare as strings.
+func compareValues(a, b any, kind fieldKind, opts parseOptions) int {
+	switch kind {
+	case fieldKindNumber:
+		af, aok := sortNumber(a)
+		bf, bok := sortNumber(b)
+		if aok && bok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+		if aok != bok {
+			return conformFirst(aok)
+		}
+	case fieldKindTime:
+		at, aok := timeFromValue(a, opts)
+		bt, bok := timeFromValue(b, opts)
+		if aok && bok {
+			return at.Compare(bt)
+		}
+		if aok != bok {
+			return conformFirst(aok)
+		}
+	case fieldKindBoolean:
+		ab, aok := a.(bool)
+		bb, bok := b.(bool)
+		if aok && bok {
+			switch {
+			case ab == bb:
+				return 0
+			case !ab:
+				return -1
+			}
+			return 1
+		}
+		if aok != bok {
+			return conformFirst(aok)
+		}
+	}
+
+	as, bs := exprString(a), exprString(b)
+	if cmp := strings.Compare(strings.ToLower(as), strings.ToLower(bs)); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(as, bs)
+}
+
+// sortConforms reports whether a non-null value compares as its column's kind rather
+// than as a string.
+func sortConforms(val any, kind fieldKind, opts parseOptions) bool {
+	switch kind {
+	case fieldKindNumber:
+		_, ok := sortNumber(val)
+		return ok
+	case fieldKindTime:
+		_, ok := timeFromValue(val, opts)
+		return ok
+	case fieldKindBoolean:
+		_, ok := val.(bool)
+		return ok
+	}
+	return true
+}
+
+func conformFirst(aConforms bool) int {
+	if aConforms {
+		return -1
+	}
+	return 1
+}
+
+func sortNumber(val any) (float64, bool) {
+	switch v := val.(type) {
+	case float64, float32, int, int32, int64, uint, uint32, uint64:
+		return toFloat64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func limitRows(rows []map[string]any, topN int) []map[string]any {
+	if topN <= 0 || topN >= len(rows) {
+		return rows
+	}
+	return rows[:topN]
+}
+
+// projectColumns keeps only the requested columns, in the requested order, renaming
+// aliased ones. It returns the key the time field ends up under, or "" when it was not
+// selected.
+func projectColumns(rows []map[string]any, descriptors []fieldDescriptor, columns []models.QueryColumn, timeField string) ([]map[string]any, []fieldDescriptor, string, error) {
+	if len(columns) == 0 {
+		return rows, descriptors, timeField, nil
+	}
+
+	descByKey := make(map[string]fieldDescriptor, len(descriptors))
+	for _, desc := range descriptors {
+		descByKey[desc.meta.Key] = desc
+	}
+
+	type projection struct {
+		from, to string
+	}
+
+	projections := make([]projection, 0, len(columns))
+	used := make(map[string]struct{}, len(columns))
+	projected := make([]fieldDescriptor, 0, len(columns))
+	newTimeField := ""
+
+	for _, col := range columns {
+		input := normalizeFieldKey(col.Field)
+		key, ok := resolveFieldKey(input, descriptors, rows)
+		if !ok {
+			return nil, nil, "", newRequestError("column %q not found", col.Field)
+		}
+
+		target := key
+		if alias := normalizeFieldKey(col.Alias); alias != "" {
+			target = alias
+		}
+		if _, dup := used[target]; dup {
+			return nil, nil, "", newRequestError("column %q selected more than once", target)
+		}
+		used[target] = struct{}{}
+		projections = append(projections, projection{from: key, to: target})
+
+		if key == timeField {
+			newTimeField = target
+		}
+
+		if desc, ok := descByKey[key]; ok {
+			if target != key {
+				desc.meta.Key = target
+				desc.meta.Label = target
+			}
+			projected = append(projected, desc)
+		} else if len(descriptors) > 0 {
+			projected = append(projected, fieldDescriptor{
+				meta: orcaField{Key: target, Label: target},
+				kind: detectKindFromRows(key, rows, fieldKindString),
+			})
+		}
+	}
+
+	out := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		shaped := make(map[string]any, len(projections))
+		for _, p := range projections {
+			if val, ok := row[p.from]; ok {
+				shaped[p.to] = val
+			}
+		}
+		out = append(out, shaped)
+	}
+
+	return out, projected, newTimeField, nil
+}
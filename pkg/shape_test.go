@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestSortRows(t *testing.T) {
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "stock", Label: "Stock"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "name"}, kind: fieldKindString},
+		{meta: orcaField{Key: "seen"}, kind: fieldKindTime},
+	}
+	day := func(d int) time.Time { return time.Date(2025, 9, d, 0, 0, 0, 0, time.UTC) }
+	rows := []map[string]any{
+		{"stock": 10.0, "name": "b", "seen": day(3)},
+		{"stock": nil, "name": "a", "seen": day(1)},
+		{"stock": 2.0, "name": "c", "seen": day(2)},
+		{"stock": 10.0, "name": "A", "seen": day(4)},
+	}
+
+	got, err := sortRows(rows, []models.QuerySort{{Field: "Stock"}, {Field: "name", Desc: true}}, descriptors, parseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"c", "b", "A", "a"}
+	for idx, name := range want {
+		if got[idx]["name"] != name {
+			t.Fatalf("position %d: expected %q got %v", idx, name, got[idx]["name"])
+		}
+	}
+
+	got, _ = sortRows(rows, []models.QuerySort{{Field: "seen", Desc: true}}, descriptors, parseOptions{})
+	if got[0]["name"] != "A" || got[3]["name"] != "a" {
+		t.Fatalf("unexpected time ordering: %v", got)
+	}
+
+	if _, err := sortRows(rows, []models.QuerySort{{Field: "missing"}}, descriptors, parseOptions{}); err == nil {
+		t.Fatal("expected error for unknown sort field")
+	}
+	if rows[0]["name"] != "b" {
+		t.Fatal("expected input rows to keep their order")
+	}
+
+	messy := []map[string]any{
+		{"name": "low", "stock": 1.0},
+		{"name": "text", "stock": "n/a"},
+		{"name": "none", "stock": nil},
+		{"name": "high", "stock": 5.0},
+	}
+	got, _ = sortRows(messy, []models.QuerySort{{Field: "stock", Desc: true}}, descriptors, parseOptions{})
+	for idx, name := range []string{"high", "low", "text", "none"} {
+		if got[idx]["name"] != name {
+			t.Fatalf("descending position %d: expected %q got %v", idx, name, got[idx]["name"])
+		}
+	}
+
+	mixed := []map[string]any{
+		{"name": "parsed", "seen": time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)},
+		{"name": "raw", "seen": "2025-09-01 08:00"},
+	}
+	eastern := parseOptions{location: time.FixedZone("UTC-5", -5*3600)}
+	if got, _ := sortRows(mixed, []models.QuerySort{{Field: "seen"}}, descriptors, parseOptions{}); got[0]["name"] != "raw" {
+		t.Fatalf("expected a zone-less string read as UTC to sort first, got %v", got)
+	}
+	if got, _ := sortRows(mixed, []models.QuerySort{{Field: "seen"}}, descriptors, eastern); got[0]["name"] != "parsed" {
+		t.Fatalf("expected a zone-less string read in the query timezone to sort last, got %v", got)
+	}
+}
+
+func TestProjectColumns(t *testing.T) {
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "stock", Label: "Stock"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "name"}, kind: fieldKindString},
+		{meta: orcaField{Key: "seen"}, kind: fieldKindTime},
+	}
+	rows := []map[string]any{
+		{"stock": 1.0, "name": "a", "seen": "2025-09-01"},
+	}
+
+	gotRows, gotDesc, timeField, err := projectColumns(rows, descriptors, []models.QueryColumn{
+		{Field: "name", Alias: "Item"},
+		{Field: "Stock"},
+		{Field: "seen", Alias: "When"},
+	}, "seen")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gotRows[0]) != 3 || gotRows[0]["Item"] != "a" || gotRows[0]["stock"] != 1.0 {
+		t.Fatalf("unexpected projected row: %v", gotRows[0])
+	}
+	if len(gotDesc) != 3 || gotDesc[0].meta.Key != "Item" || gotDesc[1].meta.Key != "stock" {
+		t.Fatalf("unexpected projected descriptors: %+v", gotDesc)
+	}
+	if timeField != "When" {
+		t.Fatalf("expected time field to follow its alias, got %q", timeField)
+	}
+
+	if _, _, _, err := projectColumns(rows, descriptors, []models.QueryColumn{{Field: "name"}, {Field: "stock", Alias: "name"}}, ""); err == nil {
+		t.Fatal("expected error for duplicate output column")
+	}
+	if got := limitRows(rows, 5); len(got) != 1 {
+		t.Fatalf("expected limit larger than rows to keep all rows, got %d", len(got))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// cachedSheets returns the sheet list, reusing a recent listSheets result when available.
+func (i *orcaInstance) cachedSheets(ctx context.Context) ([]orcaSheet, error) {
+	i.sheetCacheMu.RLock()
+	entry := i.sheetCache
+	i.sheetCacheMu.RUnlock()
+	if entry.sheets != nil && time.Since(entry.fetchedAt) < sheetCacheTTL {
+		observeCache("sheets", true)
+		return entry.sheets, nil
+	}
+	observeCache("sheets", false)
+
+	return i.refreshSheets(ctx)
+}
+
+func (i *orcaInstance) refreshSheets(ctx context.Context) ([]orcaSheet, error) {
+	sheets, err := i.listSheets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sheets == nil {
+		sheets = []orcaSheet{}
+	}
+
+	i.sheetCacheMu.Lock()
+	i.sheetCache = sheetCacheEntry{
+		sheets:    sheets,
+		fetchedAt: time.Now(),
+	}
+	i.sheetCacheMu.Unlock()
+
+	return sheets, nil
+}
+
+// resolveSheetName finds the sheet called name. A cached sheet list that yields no match
+// is refreshed once so that recently created or renamed sheets are found.
+func (i *orcaInstance) resolveSheetName(ctx context.Context, name string) (orcaSheet, error) {
+	sheets, err := i.cachedSheets(ctx)
+	if err != nil {
+		return orcaSheet{}, err
+	}
+
+	sheet, err := matchSheetName(sheets, name)
+	if err == nil {
+		return sheet, nil
+	}
+	if statusFromError(err) != http.StatusNotFound {
+		return orcaSheet{}, err
+	}
+
+	sheets, refreshErr := i.refreshSheets(ctx)
+	if refreshErr != nil {
+		return orcaSheet{}, refreshErr
+	}
+	return matchSheetName(sheets, name)
+}
+
+// matchSheetName matches name against sheet names exactly, then case-insensitively, then
+// as a case-insensitive glob. The first tier with matches wins and must match exactly one sheet.
+func matchSheetName(sheets []orcaSheet, name string) (orcaSheet, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return orcaSheet{}, newRequestError("sheet name is required")
+	}
+
+	tiers := []func(orcaSheet) bool{
+		func(s orcaSheet) bool { return s.Name == name },
+		func(s orcaSheet) bool { return strings.EqualFold(strings.TrimSpace(s.Name), name) },
+	}
+
+	if strings.ContainsAny(name, "*?[") {
+		pattern := strings.ToLower(name)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return orcaSheet{}, newRequestError("invalid sheet name pattern %q: %v", name, err)
+		}
+		tiers = append(tiers, func(s orcaSheet) bool {
+			ok, _ := path.Match(pattern, strings.ToLower(s.Name))
+			return ok
+		})
+	}
+
+	for _, match := range tiers {
+		var found []orcaSheet
+		for _, sheet := range sheets {
+			if match(sheet) {
+				found = append(found, sheet)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			names := make([]string, 0, len(found))
+			for _, sheet := range found {
+				names = append(names, sheet.Name+" ("+sheet.ID+")")
+			}
+			return orcaSheet{}, newRequestError("sheet name %q is ambiguous; matches %s", name, strings.Join(names, ", "))
+		}
+	}
+
+	return orcaSheet{}, newNotFoundError("no sheet named %q", name)
+}
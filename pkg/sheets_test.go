@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMatchSheetName(t *testing.T) {
+	sheets := []orcaSheet{
+		{ID: "1", Name: "Inventory"},
+		{ID: "2", Name: "inventory"},
+		{ID: "3", Name: "Locations"},
+		{ID: "4", Name: "Site Leeds"},
+		{ID: "5", Name: "Site York"},
+	}
+
+	tests := []struct {
+		name    string
+		wantID  string
+		wantErr bool
+	}{
+		{"Inventory", "1", false},
+		{"LOCATIONS", "3", false},
+		{"loc*", "3", false},
+		{"INVENTORY", "", true},
+		{"site *", "", true},
+		{"Missing", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := matchSheetName(sheets, tc.name)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("name %q: expected error, got %v", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("name %q: unexpected error: %v", tc.name, err)
+		}
+		if got.ID != tc.wantID {
+			t.Fatalf("name %q: expected id %q got %q", tc.name, tc.wantID, got.ID)
+		}
+	}
+
+	if _, err := matchSheetName(sheets, "Missing"); statusFromError(err) != http.StatusNotFound {
+		t.Fatalf("expected not found status, got %d", statusFromError(err))
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+// earthRadiusMeters is the mean Earth radius used for haversine distances.
+const earthRadiusMeters = 6371008.8
+
+// polygonRings holds polygons as rings of [lon, lat] positions; the first ring of each
+// polygon is its exterior and the rest are holes.
+type polygonRings [][][][2]float64
+
+// applySpatialFilter keeps the rows whose geo column lies within every area spec sets:
+// a radius around a point, a bounding box and a GeoJSON polygon. Rows without a
+// coordinate are dropped once any area is set. spec.DistanceField adds a column holding
+// each row's distance in metres from the radius centre.
+func applySpatialFilter(rows []map[string]any, descriptors []fieldDescriptor, columns []string, spec *models.QuerySpatial) ([]map[string]any, []fieldDescriptor, error) {
+	if spec == nil {
+		return rows, descriptors, nil
+	}
+
+	radius := spec.Radius
+	if radius != nil && (math.Abs(radius.Lat) > 90 || math.Abs(radius.Lon) > 180 || radius.Meters < 0) {
+		return nil, nil, newRequestError("spatial radius needs a valid lat/lon and non-negative meters")
+	}
+	if len(spec.BBox) != 0 && len(spec.BBox) != 4 {
+		return nil, nil, newRequestError("spatial bbox must be [minLon, minLat, maxLon, maxLat]")
+	}
+	var polygons polygonRings
+	if len(spec.Polygon) > 0 && string(spec.Polygon) != "null" {
+		var err error
+		if polygons, err = parseGeoJSONPolygons(spec.Polygon); err != nil {
+			return nil, nil, newRequestError("spatial polygon: %v", err)
+		}
+	}
+
+	distanceField := normalizeFieldKey(spec.DistanceField)
+	if distanceField != "" && radius == nil {
+		return nil, nil, newRequestError("spatial distanceField needs a radius centre")
+	}
+	for _, desc := range descriptors {
+		if distanceField != "" && desc.meta.Key == distanceField {
+			return nil, nil, newRequestError("spatial distanceField %q already exists", distanceField)
+		}
+	}
+
+	filtering := (radius != nil && radius.Meters > 0) || len(spec.BBox) == 4 || polygons != nil
+	if !filtering && distanceField == "" {
+		return rows, descriptors, nil
+	}
+
+	geoKey, err := resolveGeoField(spec.Field, descriptors, columns, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matches := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		point, ok := parseGeoPoint(row[geoKey])
+		if !ok {
+			if !filtering {
+				matches = append(matches, row)
+			}
+			continue
+		}
+
+		var distance float64
+		if radius != nil {
+			distance = haversineMeters(radius.Lat, radius.Lon, point.lat, point.lon)
+			if radius.Meters > 0 && distance > radius.Meters {
+				continue
+			}
+		}
+		if len(spec.BBox) == 4 && !inBBox(point, spec.BBox) {
+			continue
+		}
+		if polygons != nil && !polygons.contains(point.lon, point.lat) {
+			continue
+		}
+
+		if distanceField != "" {
+			// Rows may be shared with the sheet cache, so the column goes on a copy.
+			out := make(map[string]any, len(row)+1)
+			for key, val := range row {
+				out[key] = val
+			}
+			out[distanceField] = roundTo(distance, 1)
+			row = out
+		}
+		matches = append(matches, row)
+	}
+
+	if distanceField != "" {
+		descriptors = append(append([]fieldDescriptor(nil), descriptors...), fieldDescriptor{
+			meta:        orcaField{Key: distanceField, Label: distanceField, Type: "number"},
+			kind:        fieldKindNumber,
+			decimals:    1,
+			hasDecimals: true,
+			unit:        "lengthm",
+		})
+	}
+	return matches, descriptors, nil
+}
+
+// haversineMeters returns the great-circle distance between two coordinates.
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// inBBox reports whether point lies in a GeoJSON-order bbox; a min longitude greater
+// than the max means the box crosses the antimeridian.
+func inBBox(point geoPoint, bbox []float64) bool {
+	minLon, minLat, maxLon, maxLat := bbox[0], bbox[1], bbox[2], bbox[3]
+	if point.lat < minLat || point.lat > maxLat {
+		return false
+	}
+	if minLon <= maxLon {
+		return point.lon >= minLon && point.lon <= maxLon
+	}
+	return point.lon >= minLon || point.lon <= maxLon
+}
+
+// parseGeoJSONPolygons reads a Polygon or MultiPolygon geometry, optionally wrapped in
+// a Feature or FeatureCollection.
+func parseGeoJSONPolygons(raw json.RawMessage) (polygonRings, error) {
+	var obj struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometry    json.RawMessage   `json:"geometry"`
+		Features    []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(obj.Type) {
+	case "feature":
+		return parseGeoJSONPolygons(obj.Geometry)
+	case "featurecollection":
+		var all polygonRings
+		for _, feature := range obj.Features {
+			polygons, err := parseGeoJSONPolygons(feature)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, polygons...)
+		}
+		if len(all) == 0 {
+			return nil, newRequestError("feature collection has no polygons")
+		}
+		return all, nil
+	case "polygon":
+		var rings [][][2]float64
+		if err := json.Unmarshal(obj.Coordinates, &rings); err != nil {
+			return nil, err
+		}
+		if len(rings) == 0 || len(rings[0]) < 3 {
+			return nil, newRequestError("polygon needs an exterior ring of at least 3 positions")
+		}
+		return polygonRings{rings}, nil
+	case "multipolygon":
+		var polygons polygonRings
+		if err := json.Unmarshal(obj.Coordinates, &polygons); err != nil {
+			return nil, err
+		}
+		for _, rings := range polygons {
+			if len(rings) == 0 || len(rings[0]) < 3 {
+				return nil, newRequestError("polygon needs an exterior ring of at least 3 positions")
+			}
+		}
+		return polygons, nil
+	default:
+		return nil, newRequestError("unsupported GeoJSON type %q", obj.Type)
+	}
+}
+
+// contains reports whether (x, y) lies inside any polygon's exterior ring and outside
+// its holes.
+func (p polygonRings) contains(x, y float64) bool {
+	for _, rings := range p {
+		if !ringContains(rings[0], x, y) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if ringContains(hole, x, y) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains is the even-odd ray casting test.
+func ringContains(ring [][2]float64, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func spatialRows() ([]map[string]any, []fieldDescriptor) {
+	rows := []map[string]any{
+		{"Name": "depot", "Location": "51.5072, -0.1275"},
+		{"Name": "near", "Location": "51.5100, -0.1275"},
+		{"Name": "far", "Location": "52.2053, 0.1218"},
+		{"Name": "none", "Location": nil},
+	}
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "Name"}, kind: fieldKindString},
+		{meta: orcaField{Key: "Location"}, kind: fieldKindGeo},
+	}
+	return rows, descriptors
+}
+
+func names(rows []map[string]any) []any {
+	out := make([]any, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row["Name"])
+	}
+	return out
+}
+
+func TestHaversineMeters(t *testing.T) {
+	// London to Paris is roughly 343.5 km.
+	if d := haversineMeters(51.5072, -0.1275, 48.8566, 2.3522); math.Abs(d-343500) > 1000 {
+		t.Fatalf("unexpected distance %v", d)
+	}
+}
+
+func TestApplySpatialFilterRadius(t *testing.T) {
+	rows, descriptors := spatialRows()
+	spec := &models.QuerySpatial{
+		Radius:        &models.QueryRadius{Lat: 51.5072, Lon: -0.1275, Meters: 500},
+		DistanceField: "Distance",
+	}
+
+	filtered, descs, err := applySpatialFilter(rows, descriptors, nil, spec)
+	if err != nil {
+		t.Fatalf("applySpatialFilter: %v", err)
+	}
+	if got := names(filtered); len(got) != 2 || got[0] != "depot" || got[1] != "near" {
+		t.Fatalf("unexpected rows %v", got)
+	}
+	if filtered[0]["Distance"] != 0.0 || filtered[1]["Distance"] != 311.3 {
+		t.Fatalf("unexpected distances %v, %v", filtered[0]["Distance"], filtered[1]["Distance"])
+	}
+	if _, ok := rows[1]["Distance"]; ok {
+		t.Fatal("input rows must not be modified")
+	}
+	if last := descs[len(descs)-1]; last.meta.Key != "Distance" || last.kind != fieldKindNumber || last.unit != "lengthm" {
+		t.Fatalf("unexpected distance descriptor %+v", last)
+	}
+	if len(descriptors) != 2 {
+		t.Fatal("input descriptors must not be modified")
+	}
+}
+
+func TestApplySpatialFilterBBoxAndPolygon(t *testing.T) {
+	rows, descriptors := spatialRows()
+
+	filtered, _, err := applySpatialFilter(rows, descriptors, nil, &models.QuerySpatial{BBox: []float64{-1, 51, 1, 52}})
+	if err != nil {
+		t.Fatalf("bbox: %v", err)
+	}
+	if len(filtered) != 2 {
+		t.Fatalf("unexpected bbox rows %v", names(filtered))
+	}
+
+	// A square around Cambridge with a hole around central London that never matches,
+	// plus a London square whose hole cuts out the depot.
+	polygon := json.RawMessage(`{"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": [
+		[[[0, 52], [0.5, 52], [0.5, 52.5], [0, 52.5], [0, 52]]],
+		[[[-0.2, 51.4], [0, 51.4], [0, 51.6], [-0.2, 51.6], [-0.2, 51.4]],
+		 [[-0.13, 51.505], [-0.12, 51.505], [-0.12, 51.508], [-0.13, 51.508], [-0.13, 51.505]]]
+	]}}`)
+	filtered, _, err = applySpatialFilter(rows, descriptors, nil, &models.QuerySpatial{Field: "location", Polygon: polygon})
+	if err != nil {
+		t.Fatalf("polygon: %v", err)
+	}
+	if got := names(filtered); len(got) != 2 || got[0] != "near" || got[1] != "far" {
+		t.Fatalf("unexpected polygon rows %v", got)
+	}
+}
+
+func TestApplySpatialFilterInvalid(t *testing.T) {
+	rows, descriptors := spatialRows()
+	specs := []*models.QuerySpatial{
+		{BBox: []float64{1, 2, 3}},
+		{Radius: &models.QueryRadius{Lat: 91}},
+		{Polygon: json.RawMessage(`{"type": "Point", "coordinates": [0, 0]}`)},
+		{DistanceField: "Distance"},
+		{Radius: &models.QueryRadius{}, DistanceField: "Name"},
+		{Field: "Missing", BBox: []float64{0, 0, 1, 1}},
+	}
+	for _, spec := range specs {
+		if _, _, err := applySpatialFilter(rows, descriptors, nil, spec); statusFromError(err) != 400 {
+			t.Fatalf("expected request error for %+v, got %v", spec, err)
+		}
+	}
+}
+
+func TestInBBoxAntimeridian(t *testing.T) {
+	bbox := []float64{170, -20, -170, 0}
+	if !inBBox(geoPoint{lat: -10, lon: 175}, bbox) || !inBBox(geoPoint{lat: -10, lon: -175}, bbox) {
+		t.Fatal("expected points either side of the antimeridian to match")
+	}
+	if inBBox(geoPoint{lat: -10, lon: 0}, bbox) {
+		t.Fatal("expected a point outside the box to be excluded")
+	}
+}
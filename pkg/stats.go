@@ -0,0 +1,270 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+const (
+	queryModeStats = "stats"
+
+	defaultHistogramBuckets = 10
+	maxHistogramBuckets     = 1000
+	defaultTopK             = 10
+)
+
+// statsFields describes the long-format table a stats query returns: one row per
+// statistic, histogram bucket or frequent value, so a panel can filter on statistic.
+var statsFields = []models.Field{
+	{Key: "field", Label: "Field", GrafanaType: "string"},
+	{Key: "statistic", Label: "Statistic", GrafanaType: "string"},
+	{Key: "label", Label: "Label", GrafanaType: "string"},
+	{Key: "from", Label: "From", GrafanaType: "number"},
+	{Key: "to", Label: "To", GrafanaType: "number"},
+	{Key: "value", Label: "Value", GrafanaType: "number"},
+}
+
+var statsPercentiles = []struct {
+	name string
+	p    float64
+}{
+	{"p50", 0.50},
+	{"p90", 0.90},
+	{"p99", 0.99},
+}
+
+// computeStats summarizes the selected columns of normalized rows. Number columns get
+// count, mean, stddev, min, max, percentiles and a histogram; string-like columns get
+// their top-K values. Without selected fields every number and string-like column is
+// used, taken from columns in sheet order when there are no descriptors.
+func computeStats(rows []map[string]any, descriptors []fieldDescriptor, columns []string, spec *models.QueryStats) ([]map[string]any, error) {
+	if spec == nil {
+		spec = &models.QueryStats{}
+	}
+	if spec.Buckets < 0 || spec.BucketWidth < 0 || spec.TopK < 0 {
+		return nil, newRequestError("stats buckets, bucketWidth and topK must not be negative")
+	}
+
+	kinds := make(map[string]fieldKind, len(descriptors))
+	keys := make([]string, 0, len(descriptors))
+	for _, desc := range descriptors {
+		kinds[desc.meta.Key] = desc.kind
+		keys = append(keys, desc.meta.Key)
+	}
+	if len(keys) == 0 {
+		keys = append(keys, columns...)
+	}
+	kindOf := func(key string) fieldKind {
+		if kind, ok := kinds[key]; ok {
+			return kind
+		}
+		kind := detectKindFromRows(key, rows, fieldKindString)
+		kinds[key] = kind
+		return kind
+	}
+
+	if len(spec.Fields) > 0 {
+		selected := make([]string, 0, len(spec.Fields))
+		for _, name := range spec.Fields {
+			key, ok := resolveFieldKey(normalizeFieldKey(name), descriptors, rows)
+			if !ok {
+				return nil, newRequestError("stats field %q not found", name)
+			}
+			selected = append(selected, key)
+		}
+		keys = selected
+	}
+
+	out := make([]map[string]any, 0)
+	for _, key := range keys {
+		switch kind := kindOf(key); kind {
+		case fieldKindNumber:
+			numberRows, err := numberStats(key, rows, spec)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, numberRows...)
+		case fieldKindString, fieldKindEnum, fieldKindBarcode, fieldKindBoolean:
+			out = append(out, topValues(key, rows, spec.TopK)...)
+		default:
+			if len(spec.Fields) > 0 {
+				return nil, newRequestError("stats are not supported for %s field %q", kindName(kind), key)
+			}
+		}
+	}
+	return out, nil
+}
+
+func statRow(field, statistic, label string, from, to, value any) map[string]any {
+	return map[string]any{
+		"field":     field,
+		"statistic": statistic,
+		"label":     label,
+		"from":      from,
+		"to":        to,
+		"value":     value,
+	}
+}
+
+func numberStats(key string, rows []map[string]any, spec *models.QueryStats) ([]map[string]any, error) {
+	values := make([]float64, 0, len(rows))
+	for _, row := range rows {
+		if v, ok := row[key].(float64); ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			values = append(values, v)
+		}
+	}
+
+	out := []map[string]any{statRow(key, "count", "", nil, nil, float64(len(values)))}
+	if len(values) == 0 {
+		return out, nil
+	}
+	sort.Float64s(values)
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var stddev any
+	if len(values) > 1 {
+		squares := 0.0
+		for _, v := range values {
+			squares += (v - mean) * (v - mean)
+		}
+		stddev = math.Sqrt(squares / float64(len(values)-1))
+	}
+
+	out = append(out,
+		statRow(key, "mean", "", nil, nil, mean),
+		statRow(key, "stddev", "", nil, nil, stddev),
+		statRow(key, "min", "", nil, nil, values[0]),
+		statRow(key, "max", "", nil, nil, values[len(values)-1]),
+	)
+	for _, pct := range statsPercentiles {
+		out = append(out, statRow(key, pct.name, "", nil, nil, percentile(values, pct.p)))
+	}
+
+	buckets, err := histogram(values, spec.Buckets, spec.BucketWidth)
+	if err != nil {
+		return nil, newRequestError("stats field %q: %v", key, err)
+	}
+	for _, b := range buckets {
+		label := formatStatNumber(b.from) + "–" + formatStatNumber(b.to)
+		out = append(out, statRow(key, "bucket", label, b.from, b.to, float64(b.count)))
+	}
+	return out, nil
+}
+
+// percentile interpolates linearly between the closest ranks of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+type histogramBucket struct {
+	from, to float64
+	count    int
+}
+
+// histogram counts sorted values into equal-width buckets. A width aligns buckets to
+// multiples of it; otherwise count buckets (default 10) span min to max. Each bucket
+// holds [from, to) except the last, which includes its upper bound.
+func histogram(sorted []float64, count int, width float64) ([]histogramBucket, error) {
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+
+	var start float64
+	switch {
+	case width > 0:
+		start = math.Floor(lo/width) * width
+		// Checked as a float: tiny widths overflow int, and infinite quotients give NaN.
+		n := math.Floor((hi-start)/width) + 1
+		if !(n <= maxHistogramBuckets) {
+			return nil, errTooManyBuckets(n)
+		}
+		count = int(n)
+	case lo == hi:
+		return []histogramBucket{{from: lo, to: hi, count: len(sorted)}}, nil
+	default:
+		if count == 0 {
+			count = defaultHistogramBuckets
+		}
+		if count > maxHistogramBuckets {
+			return nil, errTooManyBuckets(float64(count))
+		}
+		start = lo
+		width = (hi - lo) / float64(count)
+	}
+
+	// Bounds are rounded so widths such as 0.1 label cleanly.
+	buckets := make([]histogramBucket, count)
+	for i := range buckets {
+		buckets[i].from = roundTo(start+float64(i)*width, 9)
+		buckets[i].to = roundTo(start+float64(i+1)*width, 9)
+	}
+	for _, v := range sorted {
+		idx := int((v - start) / width)
+		if idx >= count {
+			idx = count - 1
+		}
+		buckets[idx].count++
+	}
+	return buckets, nil
+}
+
+func errTooManyBuckets(n float64) error {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return newRequestError("histogram bucket width is too small (max %d buckets)", maxHistogramBuckets)
+	}
+	return newRequestError("histogram would have %.0f buckets (max %d)", n, maxHistogramBuckets)
+}
+
+// topValues returns the k most frequent values of key, ties in value order.
+func topValues(key string, rows []map[string]any, k int) []map[string]any {
+	if k == 0 {
+		k = defaultTopK
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		val := row[key]
+		if isEmptyCell(val) {
+			continue
+		}
+		if s, ok := stringifyValue(val).(string); ok {
+			counts[s]++
+		}
+	}
+
+	values := make([]string, 0, len(counts))
+	for s := range counts {
+		values = append(values, s)
+	}
+	sort.Slice(values, func(a, b int) bool {
+		if counts[values[a]] != counts[values[b]] {
+			return counts[values[a]] > counts[values[b]]
+		}
+		return values[a] < values[b]
+	})
+	if len(values) > k {
+		values = values[:k]
+	}
+
+	out := make([]map[string]any, 0, len(values))
+	for _, s := range values {
+		out = append(out, statRow(key, "top", s, nil, nil, float64(counts[s])))
+	}
+	return out
+}
+
+func formatStatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func statValue(rows []map[string]any, field, statistic string) any {
+	for _, row := range rows {
+		if row["field"] == field && row["statistic"] == statistic {
+			return row["value"]
+		}
+	}
+	return "missing"
+}
+
+func TestComputeStats(t *testing.T) {
+	rows := []map[string]any{
+		{"Qty": 1.0, "Status": "Open"},
+		{"Qty": 2.0, "Status": "Closed"},
+		{"Qty": 3.0, "Status": "Open"},
+		{"Qty": 4.0, "Status": "Open"},
+		{"Qty": nil, "Status": "Lost"},
+	}
+	descriptors := []fieldDescriptor{
+		{meta: orcaField{Key: "Qty", Label: "Quantity"}, kind: fieldKindNumber},
+		{meta: orcaField{Key: "Status"}, kind: fieldKindString},
+	}
+
+	stats, err := computeStats(rows, descriptors, nil, &models.QueryStats{Buckets: 3, TopK: 2})
+	if err != nil {
+		t.Fatalf("computeStats: %v", err)
+	}
+
+	if statValue(stats, "Qty", "count") != 4.0 || statValue(stats, "Qty", "mean") != 2.5 {
+		t.Fatalf("unexpected count/mean in %v", stats)
+	}
+	if sd := statValue(stats, "Qty", "stddev").(float64); math.Abs(sd-1.2909944) > 1e-6 {
+		t.Fatalf("unexpected stddev %v", sd)
+	}
+	if statValue(stats, "Qty", "p50") != 2.5 || statValue(stats, "Qty", "p90") != 3.7 {
+		t.Fatalf("unexpected percentiles in %v", stats)
+	}
+
+	var buckets []map[string]any
+	for _, row := range stats {
+		if row["statistic"] == "bucket" {
+			buckets = append(buckets, row)
+		}
+	}
+	if len(buckets) != 3 || buckets[0]["value"] != 1.0 || buckets[2]["value"] != 2.0 || buckets[2]["to"] != 4.0 {
+		t.Fatalf("unexpected histogram %v", buckets)
+	}
+
+	var top []map[string]any
+	for _, row := range stats {
+		if row["statistic"] == "top" {
+			top = append(top, row)
+		}
+	}
+	if len(top) != 2 || top[0]["label"] != "Open" || top[0]["value"] != 3.0 || top[1]["label"] != "Closed" {
+		t.Fatalf("unexpected top values %v", top)
+	}
+}
+
+func TestHistogramWidth(t *testing.T) {
+	buckets, err := histogram([]float64{0.05, 0.15, 0.25, 0.29}, 0, 0.1)
+	if err != nil {
+		t.Fatalf("histogram: %v", err)
+	}
+	if len(buckets) != 3 || buckets[1].from != 0.1 || buckets[2].count != 2 {
+		t.Fatalf("unexpected buckets %+v", buckets)
+	}
+
+	if _, err := histogram([]float64{0, 1e6}, 0, 1); statusFromError(err) != 400 {
+		t.Fatalf("expected too many buckets to be a request error, got %v", err)
+	}
+	for _, values := range [][]float64{{0, 1e10}, {1, 2}} {
+		if _, err := histogram(values, 0, 1e-300); statusFromError(err) != 400 {
+			t.Fatalf("expected a tiny width to be a request error for %v, got %v", values, err)
+		}
+	}
+}
+
+func TestComputeStatsSelectedField(t *testing.T) {
+	rows := []map[string]any{{"When": "2024-01-01"}}
+	descriptors := []fieldDescriptor{{meta: orcaField{Key: "When"}, kind: fieldKindTime}}
+
+	if _, err := computeStats(rows, descriptors, nil, &models.QueryStats{Fields: []string{"missing"}}); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+	if _, err := computeStats(rows, descriptors, nil, &models.QueryStats{Fields: []string{"when"}}); err == nil {
+		t.Fatal("expected time field to be rejected")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+// systemColumn describes a metadata key Orca adds to every row without listing it in
+// the sheet's fields.
+type systemColumn struct {
+	label string
+	kind  fieldKind
+}
+
+// orcaSystemColumns maps the lower-cased keys Orca uses for row metadata to how they are
+// typed. A sheet column of the same name always wins over the system meaning.
+var orcaSystemColumns = map[string]systemColumn{
+	"_id": {label: "ID", kind: fieldKindString},
+
+	"_created":   {label: "Created", kind: fieldKindTime},
+	"_createdat": {label: "Created", kind: fieldKindTime},
+	"createdat":  {label: "Created", kind: fieldKindTime},
+	"created":    {label: "Created", kind: fieldKindTime},
+
+	"_updated":   {label: "Updated", kind: fieldKindTime},
+	"_updatedat": {label: "Updated", kind: fieldKindTime},
+	"updatedat":  {label: "Updated", kind: fieldKindTime},
+	"updated":    {label: "Updated", kind: fieldKindTime},
+
+	"_user":      {label: "User", kind: fieldKindString},
+	"_userid":    {label: "User", kind: fieldKindString},
+	"_createdby": {label: "User", kind: fieldKindString},
+	"createdby":  {label: "User", kind: fieldKindString},
+	"user":       {label: "User", kind: fieldKindString},
+
+	"_updatedby": {label: "Updated By", kind: fieldKindString},
+	"updatedby":  {label: "Updated By", kind: fieldKindString},
+}
+
+func lookupSystemColumn(key string) (systemColumn, bool) {
+	col, ok := orcaSystemColumns[strings.ToLower(key)]
+	return col, ok
+}
+
+// sheetColumns drops the underscore-prefixed system keys from columns, leaving the ones
+// to treat as sheet fields when the sheet's metadata is unavailable.
+func sheetColumns(columns []string) []string {
+	out := make([]string, 0, len(columns))
+	for _, key := range columns {
+		if _, ok := lookupSystemColumn(key); ok && strings.HasPrefix(key, "_") {
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+// appendSystemDescriptors adds a descriptor for each system key in columns that is not
+// already a sheet field. Created and updated are typed as time when their values parse
+// as times, so either can drive the time range; the rest are strings.
+func appendSystemDescriptors(list []fieldDescriptor, mapping map[string]fieldDescriptor, columns []string, rows []map[string]any, opts parseOptions) ([]fieldDescriptor, map[string]fieldDescriptor) {
+	for _, key := range columns {
+		col, ok := lookupSystemColumn(key)
+		if !ok {
+			continue
+		}
+		if _, exists := mapping[key]; exists {
+			continue
+		}
+
+		meta := orcaField{Key: key, Label: col.label, Type: "system"}
+		fieldOpts := opts.forField(meta)
+		desc := fieldDescriptor{meta: meta, kind: col.kind, opts: fieldOpts, system: true}
+		if col.kind == fieldKindTime {
+			detection := detectKind(key, rows, fieldKindTime, fieldOpts)
+			desc.kind = detection.kind
+			if detection.kind == fieldKindTime {
+				desc.confidence = detection.confidence
+			}
+		}
+
+		list = append(list, desc)
+		mapping[key] = desc
+	}
+	return list, mapping
+}
+
+// hideSystemColumns removes system descriptors from list, except the one keyed keep.
+func hideSystemColumns(list []fieldDescriptor, keep string) []fieldDescriptor {
+	out := make([]fieldDescriptor, 0, len(list))
+	for _, desc := range list {
+		if desc.system && desc.meta.Key != keep {
+			continue
+		}
+		out = append(out, desc)
+	}
+	return out
+}
+
+// systemTimeFieldInfos lists created and updated as time fields for field pickers, which
+// only see the sheet's metadata; their keys resolve to the actual row keys by label.
+func systemTimeFieldInfos(fieldsMeta []orcaField) []models.Field {
+	var out []models.Field
+	for _, name := range []string{"created", "updated"} {
+		taken := false
+		for _, f := range fieldsMeta {
+			if strings.EqualFold(f.Key, name) || strings.EqualFold(f.Label, name) {
+				taken = true
+				break
+			}
+		}
+		if taken {
+			continue
+		}
+		col := orcaSystemColumns[name]
+		out = append(out, models.Field{Key: name, Label: col.label, Type: "system", GrafanaType: "time", IsTime: true})
+	}
+	return out
+}
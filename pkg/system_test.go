@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSheetColumns(t *testing.T) {
+	got := sheetColumns([]string{"_id", "Name", "_createdAt", "created", "_other"})
+	if want := []string{"Name", "created", "_other"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAppendSystemDescriptors(t *testing.T) {
+	rows := []map[string]any{
+		{"_id": "a1", "Name": "box", "_createdAt": "2024-03-01T09:30:00Z", "_updatedAt": "soon", "_user": "sam@example.com"},
+		{"_id": "a2", "Name": "bag", "_createdAt": "2024-03-02T10:00:00Z", "_updatedAt": "later", "_user": "kim@example.com"},
+	}
+	columns := []string{"_id", "Name", "_createdAt", "_updatedAt", "_user"}
+	list := []fieldDescriptor{{meta: orcaField{Key: "Name"}, kind: fieldKindString}}
+	mapping := map[string]fieldDescriptor{"Name": list[0]}
+
+	list, mapping = appendSystemDescriptors(list, mapping, columns, rows, parseOptions{})
+	if len(list) != 5 || len(mapping) != 5 {
+		t.Fatalf("unexpected descriptors %+v", list)
+	}
+
+	byKey := make(map[string]fieldDescriptor)
+	for _, desc := range list {
+		byKey[desc.meta.Key] = desc
+	}
+	if d := byKey["_id"]; !d.system || d.kind != fieldKindString || d.meta.Label != "ID" {
+		t.Fatalf("unexpected _id descriptor %+v", d)
+	}
+	if d := byKey["_createdAt"]; d.kind != fieldKindTime || d.meta.Label != "Created" {
+		t.Fatalf("unexpected created descriptor %+v", d)
+	}
+	if d := byKey["_updatedAt"]; d.kind != fieldKindString {
+		t.Fatalf("expected unparseable updated values to stay strings, got %+v", d)
+	}
+	if byKey["Name"].system {
+		t.Fatal("sheet field marked as system")
+	}
+
+	normalized := normalizeRows(rows, mapping)
+	if _, ok := normalized[0]["_createdAt"].(time.Time); !ok {
+		t.Fatalf("expected created to normalize to a time, got %T", normalized[0]["_createdAt"])
+	}
+	if key, ok := resolveTimeField("created", list, normalized); !ok || key != "_createdAt" {
+		t.Fatalf("expected created to resolve to _createdAt, got %q", key)
+	}
+}
+
+func TestAppendSystemDescriptorsKeepsSheetFields(t *testing.T) {
+	rows := []map[string]any{{"user": "Warehouse"}}
+	list := []fieldDescriptor{{meta: orcaField{Key: "user", Label: "user"}, kind: fieldKindString}}
+	mapping := map[string]fieldDescriptor{"user": list[0]}
+
+	list, _ = appendSystemDescriptors(list, mapping, []string{"user"}, rows, parseOptions{})
+	if len(list) != 1 || list[0].system {
+		t.Fatalf("expected the sheet field to win, got %+v", list)
+	}
+}
+
+func TestHideSystemColumns(t *testing.T) {
+	list := []fieldDescriptor{
+		{meta: orcaField{Key: "Name"}},
+		{meta: orcaField{Key: "_id"}, system: true},
+		{meta: orcaField{Key: "_createdAt"}, system: true},
+	}
+	got := hideSystemColumns(list, "_createdAt")
+	if len(got) != 2 || got[0].meta.Key != "Name" || got[1].meta.Key != "_createdAt" {
+		t.Fatalf("unexpected descriptors %+v", got)
+	}
+}
+
+func TestSystemTimeFieldInfos(t *testing.T) {
+	got := systemTimeFieldInfos([]orcaField{{Key: "Updated", Label: "Updated"}})
+	if len(got) != 1 || got[0].Key != "created" || got[0].GrafanaType != "time" {
+		t.Fatalf("unexpected fields %+v", got)
+	}
+}
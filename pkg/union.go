@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+const defaultSourceField = "Sheet"
+
+type unionPart struct {
+	name string
+	data sheetData
+}
+
+func isUnionQuery(query models.OrcaQuery) bool {
+	return len(query.SheetIDs) > 0 || strings.TrimSpace(query.SheetPattern) != ""
+}
+
+// loadUnion loads every sheet selected by the query and concatenates their rows,
+// tagging each row with the name of the sheet it came from.
+func (i *orcaInstance) loadUnion(ctx context.Context, query models.OrcaQuery, limit, skip int, opts parseOptions) (sheetData, error) {
+	sheets, err := i.cachedSheets(ctx)
+	if err != nil {
+		return sheetData{}, err
+	}
+
+	selected, err := selectUnionSheets(sheets, query)
+	if err != nil {
+		return sheetData{}, err
+	}
+
+	parts := make([]unionPart, 0, len(selected))
+	for _, sheet := range selected {
+		data, err := i.loadSheet(ctx, sheet.ID, limit, skip, opts)
+		if err != nil {
+			return sheetData{}, err
+		}
+		parts = append(parts, unionPart{name: sheet.Name, data: data})
+	}
+
+	sourceField := strings.TrimSpace(query.SourceField)
+	if sourceField == "" {
+		sourceField = defaultSourceField
+	}
+
+	return unionSheetData(parts, sourceField)
+}
+
+// selectUnionSheets returns the sheets named by SheetID, SheetIDs and SheetPattern in that
+// order, without duplicates. IDs missing from sheets are kept and labelled with their ID.
+func selectUnionSheets(sheets []orcaSheet, query models.OrcaQuery) ([]orcaSheet, error) {
+	byID := make(map[string]orcaSheet, len(sheets))
+	for _, sheet := range sheets {
+		byID[sheet.ID] = sheet
+	}
+
+	seen := make(map[string]struct{})
+	selected := make([]orcaSheet, 0)
+	add := func(sheet orcaSheet) {
+		if _, ok := seen[sheet.ID]; ok {
+			return
+		}
+		if sheet.Name == "" {
+			sheet.Name = sheet.ID
+		}
+		seen[sheet.ID] = struct{}{}
+		selected = append(selected, sheet)
+	}
+
+	ids := append([]string{query.SheetID}, query.SheetIDs...)
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if sheet, ok := byID[id]; ok {
+			add(sheet)
+		} else {
+			add(orcaSheet{ID: id})
+		}
+	}
+
+	if pattern := strings.TrimSpace(query.SheetPattern); pattern != "" {
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, newRequestError("invalid sheetPattern %q: %v", query.SheetPattern, err)
+		}
+		for _, sheet := range sheets {
+			if ok, _ := path.Match(pattern, strings.ToLower(sheet.Name)); ok {
+				add(sheet)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, newRequestError("no sheets match the query")
+	}
+
+	return selected, nil
+}
+
+// unionSheetData concatenates the rows of parts. Columns are aligned by key, then label,
+// then canonicalized key; columns whose detected kinds disagree across sheets are
+// re-detected over the combined rows and fall back to strings. A sourceField that names
+// an existing column is a request error rather than overwriting that column.
+func unionSheetData(parts []unionPart, sourceField string) (sheetData, error) {
+	for _, part := range parts {
+		collides := false
+		for _, desc := range part.data.descList {
+			collides = collides || strings.EqualFold(desc.meta.Key, sourceField) || strings.EqualFold(desc.meta.Label, sourceField)
+		}
+		for _, row := range part.data.rows {
+			_, ok := row[sourceField]
+			collides = collides || ok
+		}
+		if collides {
+			return sheetData{}, newRequestError("source field %q is already a column of sheet %q; set sourceField to another name", sourceField, part.name)
+		}
+	}
+
+	descList := make([]fieldDescriptor, 0)
+	descIndex := make(map[string]int)
+	conflicts := make(map[string]struct{})
+	offenders := make(map[string]int)
+
+	for _, part := range parts {
+		for _, desc := range part.data.descList {
+			if _, ok := descIndex[desc.meta.Key]; ok {
+				continue
+			}
+			if _, ok := matchUnionColumn(desc, descList, nil); ok {
+				continue
+			}
+			descIndex[desc.meta.Key] = len(descList)
+			descList = append(descList, desc)
+		}
+	}
+
+	rows := make([]map[string]any, 0)
+	for _, part := range parts {
+		keyMap := make(map[string]string, len(part.data.descList))
+		claimed := make(map[string]struct{}, len(part.data.descList))
+		for _, desc := range part.data.descList {
+			target, ok := matchUnionColumn(desc, descList, claimed)
+			if !ok {
+				continue
+			}
+			claimed[target] = struct{}{}
+			keyMap[desc.meta.Key] = target
+
+			idx := descIndex[target]
+			merged := descList[idx]
+			if merged.kind != desc.kind {
+				conflicts[target] = struct{}{}
+			}
+			offenders[target] += desc.offenders
+			if merged.kind == fieldKindEnum && desc.kind == fieldKindEnum {
+				merged.enumValues = mergeEnumValues(merged.enumValues, desc.enumValues)
+			}
+			if desc.hasDecimals && desc.decimals > merged.decimals {
+				merged.decimals = desc.decimals
+				merged.hasDecimals = true
+			}
+			descList[idx] = merged
+		}
+
+		for _, row := range part.data.rows {
+			out := make(map[string]any, len(row)+1)
+			for key, val := range row {
+				if target, ok := keyMap[key]; ok {
+					key = target
+				}
+				out[key] = val
+			}
+			out[sourceField] = part.name
+			rows = append(rows, out)
+		}
+	}
+
+	for key, count := range offenders {
+		descList[descIndex[key]].offenders = count
+	}
+
+	for key := range conflicts {
+		idx := descIndex[key]
+		desc := descList[idx]
+		desc.kind = detectKindFromRows(key, rows, fieldKindString)
+		for _, row := range rows {
+			val, ok := row[key]
+			if !ok {
+				continue
+			}
+			if desc.kind == fieldKindString {
+				row[key] = stringifyValue(val)
+			} else {
+				row[key] = normalizeValue(val, desc.kind, desc.opts)
+			}
+		}
+		if desc.kind != fieldKindNumber && desc.kind != fieldKindGeo {
+			desc.decimals = 0
+			desc.hasDecimals = false
+		}
+		descList[idx] = desc
+	}
+
+	sourceDesc := fieldDescriptor{
+		meta: orcaField{Key: sourceField, Label: sourceField},
+		kind: fieldKindString,
+	}
+	descList = append([]fieldDescriptor{sourceDesc}, descList...)
+
+	descMap := make(map[string]fieldDescriptor, len(descList))
+	for _, desc := range descList {
+		descMap[desc.meta.Key] = desc
+	}
+
+	sheetIDs := make([]string, 0, len(parts))
+	for _, part := range parts {
+		sheetIDs = append(sheetIDs, part.data.sheetID)
+	}
+
+	return sheetData{
+		sheetID:  strings.Join(sheetIDs, ","),
+		rows:     rows,
+		descList: descList,
+		descMap:  descMap,
+		columns:  appendDescriptorColumns(nil, descList),
+	}, nil
+}
+
+// matchUnionColumn finds the union column desc should be merged into, skipping columns
+// already claimed by another column of the same sheet.
+func matchUnionColumn(desc fieldDescriptor, columns []fieldDescriptor, claimed map[string]struct{}) (string, bool) {
+	candidates := make([]fieldDescriptor, 0, len(columns))
+	for _, col := range columns {
+		if _, ok := claimed[col.meta.Key]; ok {
+			continue
+		}
+		candidates = append(candidates, col)
+	}
+
+	if key, ok := resolveFieldKey(desc.meta.Key, candidates, nil); ok {
+		return key, true
+	}
+	if desc.meta.Label != "" {
+		return resolveFieldKey(desc.meta.Label, candidates, nil)
+	}
+	return "", false
+}
+
+func stringifyValue(val any) any {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64, float32, int, int32, int64, uint, uint32, uint64:
+		return strconv.FormatFloat(toFloat64(v), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestUnionSheetData(t *testing.T) {
+	parts := []unionPart{
+		{
+			name: "Leeds",
+			data: sheetData{
+				sheetID: "a",
+				descList: []fieldDescriptor{
+					{meta: orcaField{Key: "Qty", Label: "Quantity"}, kind: fieldKindNumber},
+					{meta: orcaField{Key: "Code"}, kind: fieldKindNumber},
+				},
+				rows: []map[string]any{{"Qty": 3.0, "Code": 10.0}},
+			},
+		},
+		{
+			name: "York",
+			data: sheetData{
+				sheetID: "b",
+				descList: []fieldDescriptor{
+					{meta: orcaField{Key: "quantity"}, kind: fieldKindNumber, decimals: 2, hasDecimals: true},
+					{meta: orcaField{Key: "code"}, kind: fieldKindString},
+				},
+				rows: []map[string]any{{"quantity": 1.25, "code": "A-10"}},
+			},
+		},
+	}
+
+	got, err := unionSheetData(parts, "Site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(got.rows))
+	}
+	if got.rows[1]["Qty"] != 1.25 || got.rows[1]["Site"] != "York" {
+		t.Fatalf("expected aligned row tagged with source sheet, got %v", got.rows[1])
+	}
+	if got.descList[0].meta.Key != "Site" {
+		t.Fatalf("expected source column first, got %q", got.descList[0].meta.Key)
+	}
+	if d := got.descMap["Qty"]; !d.hasDecimals || d.decimals != 2 {
+		t.Fatalf("expected merged decimals of 2, got %+v", d)
+	}
+	if got.descMap["Code"].kind != fieldKindString {
+		t.Fatalf("expected conflicting column to reconcile to string, got %v", got.descMap["Code"].kind)
+	}
+	if got.rows[0]["Code"] != "10" {
+		t.Fatalf("expected numeric value stringified after reconciliation, got %#v", got.rows[0]["Code"])
+	}
+
+	if _, err := unionSheetData(parts, "code"); statusFromError(err) != 400 {
+		t.Fatalf("expected a source field naming a column to be rejected, got %v", err)
+	}
+}
+
+func TestSelectUnionSheets(t *testing.T) {
+	sheets := []orcaSheet{
+		{ID: "1", Name: "Site Leeds"},
+		{ID: "2", Name: "Site York"},
+		{ID: "3", Name: "Archive"},
+	}
+
+	got, err := selectUnionSheets(sheets, models.OrcaQuery{SheetIDs: []string{"3"}, SheetPattern: "site *"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0].ID != "3" || got[1].ID != "1" || got[2].ID != "2" {
+		t.Fatalf("unexpected selection: %v", got)
+	}
+
+	if _, err := selectUnionSheets(sheets, models.OrcaQuery{SheetPattern: "nothing*"}); err == nil {
+		t.Fatal("expected error when no sheet matches")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// measurementUnits maps unit suffixes typed after numbers to Grafana unit ids, longest
+// suffix first so "km/h" is not read as "h". Single letters that are commonly used for
+// other things (m for million, s, t) are left out.
+var measurementUnits = []struct{ suffix, unit string }{
+	{"km/h", "velocitykmh"},
+	{"m/s", "velocityms"},
+	{"mph", "velocitymph"},
+	{"°C", "celsius"},
+	{"℃", "celsius"},
+	{"°F", "fahrenheit"},
+	{"℉", "fahrenheit"},
+	{"kg", "masskg"},
+	{"mg", "massmg"},
+	{"lbs", "masslb"},
+	{"lb", "masslb"},
+	{"g", "massg"},
+	{"mm", "lengthmm"},
+	{"km", "lengthkm"},
+	{"ft", "lengthft"},
+	{"mi", "lengthmi"},
+	{"ml", "mlitre"},
+	{"mL", "mlitre"},
+	{"L", "litre"},
+	{"kWh", "kwatth"},
+	{"kW", "kwatt"},
+	{"W", "watt"},
+	{"V", "volt"},
+	{"ms", "ms"},
+}
+
+// stripMeasurementUnit removes a known unit suffix from a number such as "12.5 kg".
+func stripMeasurementUnit(s string) (string, string, bool) {
+	for _, m := range measurementUnits {
+		if !strings.HasSuffix(s, m.suffix) {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimSuffix(s, m.suffix))
+		if rest == "" {
+			return s, "", false
+		}
+		last := rest[len(rest)-1]
+		if last < '0' || last > '9' {
+			return s, "", false
+		}
+		return rest, m.unit, true
+	}
+	return s, "", false
+}
+
+// unitFromFormat infers a unit from the Orca field format, e.g. "percent" or "currency (€)".
+func unitFromFormat(f orcaField) string {
+	format := strings.TrimSpace(f.Format)
+	if format == "" {
+		return ""
+	}
+	lower := strings.ToLower(format)
+	if strings.Contains(lower, "percent") || strings.Contains(format, "%") {
+		return "percent"
+	}
+	for _, token := range currencyTokens {
+		if strings.Contains(format, token) {
+			return currencyUnits[token]
+		}
+	}
+	return ""
+}
+
+// fieldConfig builds the Grafana field config for a column: its label as display name, the
+// inferred unit and decimals, and any per-field overrides from the query.
+func fieldConfig(desc fieldDescriptor) *data.FieldConfig {
+	cfg := &data.FieldConfig{}
+
+	if desc.meta.Label != "" && desc.meta.Label != desc.meta.Key {
+		cfg.DisplayNameFromDS = desc.meta.Label
+	}
+	if desc.kind == fieldKindNumber {
+		cfg.Unit = desc.unit
+		if desc.hasDecimals && desc.decimals > 0 {
+			d := uint16(desc.decimals)
+			cfg.Decimals = &d
+		}
+	}
+
+	if desc.link {
+		cfg.Links = linkConfig()
+	}
+
+	if desc.kind == fieldKindEnum && len(desc.enumValues) > 0 {
+		cfg.TypeConfig = &data.FieldTypeConfig{Enum: &data.EnumFieldConfig{Text: desc.enumValues}}
+	}
+
+	if overrides, ok := desc.opts.fieldOptions(desc.meta); ok {
+		if name := strings.TrimSpace(overrides.DisplayName); name != "" {
+			cfg.DisplayNameFromDS = name
+		}
+		if unit := strings.TrimSpace(overrides.Unit); unit != "" {
+			cfg.Unit = unit
+		}
+		if overrides.Decimals != nil && *overrides.Decimals >= 0 {
+			d := uint16(*overrides.Decimals)
+			cfg.Decimals = &d
+		}
+		if overrides.Min != nil {
+			v := data.ConfFloat64(*overrides.Min)
+			cfg.Min = &v
+		}
+		if overrides.Max != nil {
+			v := data.ConfFloat64(*overrides.Max)
+			cfg.Max = &v
+		}
+	}
+
+	if cfg.DisplayNameFromDS == "" && cfg.Unit == "" && cfg.Decimals == nil && cfg.Min == nil && cfg.Max == nil && cfg.TypeConfig == nil && cfg.Links == nil {
+		return nil
+	}
+	return cfg
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"orcascan-orcascan-datasource/pkg/models"
+)
+
+func TestParseNumberStringMeasurementUnits(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+		unit  string
+	}{
+		{"12.5 kg", 12.5, "masskg"},
+		{"21°C", 21, "celsius"},
+		{"80 km/h", 80, "velocitykmh"},
+		{"250ml", 250, "mlitre"},
+	}
+
+	for _, tc := range tests {
+		got, ok := parseNumberString(tc.input, 0)
+		if !ok || got.value != tc.value || got.unit != tc.unit {
+			t.Fatalf("%q: expected %v %q, got %+v (ok=%v)", tc.input, tc.value, tc.unit, got, ok)
+		}
+	}
+
+	if _, ok := parseNumberString("kg", 0); ok {
+		t.Fatal("expected a bare unit not to parse as a number")
+	}
+}
+
+func TestFieldConfig(t *testing.T) {
+	minWeight, decimals := 0.0, 1
+	opts := parseOptions{fields: map[string]models.QueryFieldOptions{
+		"Weight": {DisplayName: "Net weight", Min: &minWeight, Decimals: &decimals},
+	}}
+	fields := []orcaField{
+		{Key: "weight", Label: "Weight"},
+		{Key: "share", Label: "Share", Format: "Percent"},
+		{Key: "code"},
+	}
+	rows := []map[string]any{
+		{"weight": "12.25 kg", "share": "12.5", "code": "abc"},
+	}
+
+	descList, _ := buildFieldDescriptors(fields, rows, opts)
+	infos := buildFieldInfos(descList, "")
+
+	weight := infos[0].Config
+	if weight == nil || weight.DisplayNameFromDS != "Net weight" || weight.Unit != "masskg" {
+		t.Fatalf("unexpected weight config: %+v", weight)
+	}
+	if weight.Min == nil || *weight.Min != 0 || weight.Decimals == nil || *weight.Decimals != 1 {
+		t.Fatalf("expected min and decimals overrides, got %+v", weight)
+	}
+	if infos[0].Decimals == nil || *infos[0].Decimals != 1 {
+		t.Fatalf("expected decimals override to be mirrored on the field, got %v", infos[0].Decimals)
+	}
+
+	share := infos[1].Config
+	if share == nil || share.Unit != "percent" || share.DisplayNameFromDS != "Share" {
+		t.Fatalf("expected unit from the Orca format, got %+v", share)
+	}
+
+	if infos[2].Config != nil {
+		t.Fatalf("expected no config for a plain column, got %+v", infos[2].Config)
+	}
+}
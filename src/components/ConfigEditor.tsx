@@ -1,7 +1,14 @@
 import React from 'react';
 import type { DataSourcePluginOptionsEditorProps } from '@grafana/data';
-import { InlineField, SecretInput, Stack } from '@grafana/ui';
-import type { OrcaDataSourceOptions, OrcaSecureJsonData } from '../types';
+import { InlineField, InlineSwitch, Input, RadioButtonGroup, SecretInput, Stack } from '@grafana/ui';
+import type { OrcaDataSourceOptions, OrcaDateOrder, OrcaSecureJsonData } from '../types';
+
+const dateOrderOptions: Array<{ label: string; value: OrcaDateOrder }> = [
+  { label: 'Auto', value: 'auto' },
+  { label: 'DD/MM/YYYY', value: 'DMY' },
+  { label: 'MM/DD/YYYY', value: 'MDY' },
+  { label: 'YYYY/MM/DD', value: 'YMD' },
+];
 
 type Props = DataSourcePluginOptionsEditorProps<OrcaDataSourceOptions, OrcaSecureJsonData>;
 
@@ -16,6 +23,34 @@ export const ConfigEditor: React.FC<Props> = ({ options, onOptionsChange }) => {
     });
   };
 
+  const onTimezoneChange = (v: string) => {
+    onOptionsChange({
+      ...options,
+      jsonData: { ...options.jsonData, timezone: v.trim() || undefined },
+    });
+  };
+
+  const onDateOrderChange = (v: OrcaDateOrder) => {
+    onOptionsChange({
+      ...options,
+      jsonData: { ...options.jsonData, dateOrder: v === 'auto' ? undefined : v },
+    });
+  };
+
+  const onKeepBarcodesChange = (v: boolean) => {
+    onOptionsChange({
+      ...options,
+      jsonData: { ...options.jsonData, keepBarcodesAsText: v },
+    });
+  };
+
+  const onSystemColumnsChange = (v: boolean) => {
+    onOptionsChange({
+      ...options,
+      jsonData: { ...options.jsonData, includeSystemColumns: v },
+    });
+  };
+
   const onResetApiKey = () => {
     onOptionsChange({
       ...options,
@@ -37,6 +72,53 @@ export const ConfigEditor: React.FC<Props> = ({ options, onOptionsChange }) => {
         />
       </InlineField>
 
+      <InlineField
+        label="Timezone"
+        tooltip="IANA timezone for Orca dates without an offset, for example Australia/Sydney. Leave blank to use the dashboard timezone."
+        labelWidth={20}
+      >
+        <Input
+          value={options.jsonData.timezone ?? ''}
+          placeholder="Dashboard timezone"
+          onChange={(e) => onTimezoneChange(e.currentTarget.value)}
+          width={50}
+        />
+      </InlineField>
+
+      <InlineField
+        label="Date order"
+        tooltip="How dates such as 03/04/2025 are read. Auto picks the only order that fits every value in a column."
+        labelWidth={20}
+      >
+        <RadioButtonGroup
+          options={dateOrderOptions}
+          value={options.jsonData.dateOrder ?? 'auto'}
+          onChange={onDateOrderChange}
+        />
+      </InlineField>
+
+      <InlineField
+        label="Barcodes as text"
+        tooltip="Keep columns named like barcodes (Barcode, SKU, EAN, UPC, GTIN) as text so leading zeros are not lost."
+        labelWidth={20}
+      >
+        <InlineSwitch
+          value={options.jsonData.keepBarcodesAsText ?? true}
+          onChange={(e) => onKeepBarcodesChange(e.currentTarget.checked)}
+        />
+      </InlineField>
+
+      <InlineField
+        label="System columns"
+        tooltip="Return Orca's row metadata (ID, created, updated, user) as columns. Created and updated can be used as the time field either way."
+        labelWidth={20}
+      >
+        <InlineSwitch
+          value={options.jsonData.includeSystemColumns ?? false}
+          onChange={(e) => onSystemColumnsChange(e.currentTarget.checked)}
+        />
+      </InlineField>
+
       <div>Click “Save &amp; test” to verify your connection.</div>
     </Stack>
   );
@@ -1,8 +1,15 @@
 import React, { useMemo, useState } from 'react';
 import type { QueryEditorProps } from '@grafana/data';
-import { InlineField, Input, Select, Stack, Text } from '@grafana/ui';
+import { InlineField, Input, RadioButtonGroup, Select, Stack, Text } from '@grafana/ui';
 import { DataSource } from '../datasource';
-import type { OrcaDataSourceOptions, OrcaQuery } from '../types';
+import type { OrcaDataSourceOptions, OrcaQuery, OrcaQueryMode } from '../types';
+
+const modeOptions: Array<{ label: string; value: OrcaQueryMode }> = [
+  { label: 'Rows', value: 'rows' },
+  { label: 'Data quality', value: 'quality' },
+  { label: 'Statistics', value: 'stats' },
+  { label: 'Geohash bins', value: 'geohash' },
+];
 
 type Props = QueryEditorProps<DataSource, OrcaQuery, OrcaDataSourceOptions>;
 
@@ -56,7 +63,7 @@ export const QueryEditor: React.FC<Props> = ({ datasource, query, onChange, onRu
       <Text variant="bodySmall" color="secondary">
         2. (Optional) Enter the timestamp column that should drive Grafana’s time range. Leave blank for table views.
       </Text>
-      <InlineField label="Time field" labelWidth={14} tooltip="Type the exact field name, for example Release Date.">
+      <InlineField label="Time field" labelWidth={14} tooltip="Type the exact field name, for example Release Date, or created / updated for when rows were added or changed.">
         <Input
           value={timeField ?? ''}
           placeholder="Type field name"
@@ -74,6 +81,18 @@ export const QueryEditor: React.FC<Props> = ({ datasource, query, onChange, onRu
           width={30}
         />
       </InlineField>
+
+      <Text variant="bodySmall" color="secondary">
+        3. (Optional) Switch to a data-quality report or summary statistics of the sheet&apos;s columns, or geohash cell counts for heatmaps, instead of its rows.
+      </Text>
+      <InlineField label="Mode" labelWidth={14}>
+        <RadioButtonGroup
+          options={modeOptions}
+          value={query.mode ?? 'rows'}
+          disabled={!query.sheetId}
+          onChange={(mode) => applyPatchAndRun({ mode: mode === 'rows' ? undefined : mode })}
+        />
+      </InlineField>
     </Stack>
   );
 };
@@ -7,6 +7,7 @@ import {
   Field,
   FieldType,
   FieldConfig,
+  dataFrameFromJSON,
   dateTime,
 } from '@grafana/data';
 import { getBackendSrv } from '@grafana/runtime';
@@ -69,6 +70,7 @@ export class DataSource extends DataSourceApi<OrcaQuery, OrcaDataSourceOptions>
           query: {
             ...target,
             range,
+            dashboardTimezone: this.resolveTimezone(req.timezone),
           },
         }) as Promise<OrcaQueryResponse>
       )
@@ -78,12 +80,24 @@ export class DataSource extends DataSourceApi<OrcaQuery, OrcaDataSourceOptions>
     return { data: frames };
   }
 
+  private resolveTimezone(timezone?: string): string | undefined {
+    if (!timezone || timezone === 'browser') {
+      return Intl.DateTimeFormat().resolvedOptions().timeZone;
+    }
+    return timezone;
+  }
+
   async metricFindQuery(_query: string) {
     return [];
   }
 
   private toDataFrames(query: OrcaQuery, response: OrcaQueryResponse): DataFrame[] {
-    const rows: Array<Record<string, any>> = Array.isArray(response?.rows) ? response.rows : [];
+    if (Array.isArray(response?.frames)) {
+      return response.frames.map((json) => ({ ...dataFrameFromJSON(json), refId: query.refId }));
+    }
+
+    const rows: Array<Record<string, any>> =
+      response?.type === 'FeatureCollection' ? this.featuresToRows(response) : Array.isArray(response?.rows) ? response.rows : [];
     const timeField = response?.timeField ?? query.timeField;
     const fieldInfos: OrcaFieldInfo[] =
       Array.isArray(response?.fields) && response.fields.length
@@ -96,8 +110,8 @@ export class DataSource extends DataSourceApi<OrcaQuery, OrcaDataSourceOptions>
     const computedDecimals = this.computeDecimalMap(rows);
 
     const fieldPairs = fieldInfos.map((info) => {
-        const config: FieldConfig = {};
-        if (info.label && info.label !== info.key) {
+        const config: FieldConfig = { ...info.config };
+        if (!config.displayNameFromDS && info.label && info.label !== info.key) {
           config.displayName = info.label;
         }
         const fieldType = this.mapGrafanaType(info.grafanaType);
@@ -138,6 +152,11 @@ export class DataSource extends DataSourceApi<OrcaQuery, OrcaDataSourceOptions>
           }
         }
 
+        if (fieldType === FieldType.enum) {
+          (field.values as any[]).push(this.enumIndex(value, field.config));
+          return;
+        }
+
         (field.values as any[]).push(this.normalizeValue(value, fieldType));
       });
     });
@@ -153,6 +172,16 @@ export class DataSource extends DataSourceApi<OrcaQuery, OrcaDataSourceOptions>
     return [frame];
   }
 
+  /** Flattens GeoJSON features into rows of their properties plus latitude/longitude. */
+  private featuresToRows(response: OrcaQueryResponse): Array<Record<string, any>> {
+    const features = Array.isArray(response.features) ? response.features : [];
+    return features.map((feature) => ({
+      ...feature.properties,
+      latitude: feature.geometry?.coordinates[1] ?? null,
+      longitude: feature.geometry?.coordinates[0] ?? null,
+    }));
+  }
+
   private buildFallbackFields(rows: Array<Record<string, any>>, timeField?: string): OrcaFieldInfo[] {
     const seen = new Set<string>();
     const order: OrcaFieldInfo[] = [];
@@ -456,11 +485,24 @@ export class DataSource extends DataSourceApi<OrcaQuery, OrcaDataSourceOptions>
         return FieldType.boolean;
       case 'time':
         return FieldType.time;
+      case 'enum':
+        return FieldType.enum;
+      case 'other':
+        return FieldType.other;
       default:
         return FieldType.string;
     }
   }
 
+  /** Enum fields hold indexes into config.type.enum.text. */
+  private enumIndex(value: any, config: FieldConfig) {
+    if (value === null || value === undefined) {
+      return null;
+    }
+    const index = config.type?.enum?.text?.indexOf(String(value).trim()) ?? -1;
+    return index >= 0 ? index : null;
+  }
+
   private normalizeValue(value: any, type: FieldType) {
     if (value === null || value === undefined) {
       return null;
@@ -1,25 +1,148 @@
-import type { DataSourceJsonData } from '@grafana/data';
+import type { DataFrameJSON, DataSourceJsonData, FieldConfig } from '@grafana/data';
 import type { DataQuery } from '@grafana/schema';
 
 export interface OrcaDataSourceOptions extends DataSourceJsonData {
   // We won't show this in the UI; backend will default if empty.
   baseUrl?: string;
+  /** IANA timezone applied to Orca date values without an offset. */
+  timezone?: string;
+  /** How numeric dates such as 03/04/2025 are read. Defaults to auto-detection. */
+  dateOrder?: OrcaDateOrder;
+  /** Decimal separator used when typing numbers into sheets. Inferred per value when unset. */
+  decimalSeparator?: '.' | ',';
+  /** Keep columns named like barcodes (SKU, EAN, UPC, GTIN...) as text. Defaults to true. */
+  keepBarcodesAsText?: boolean;
+  /** Values per column read by type detection. Defaults to 500. */
+  detectionSampleSize?: number;
+  /** Share of sampled values that must parse as a type, 0-1. Defaults to 0.98. */
+  detectionThreshold?: number;
+  /** Return Orca's row metadata (_id, created, updated, user) as columns. Defaults to false. */
+  includeSystemColumns?: boolean;
 }
 
+export type OrcaQueryMode = 'rows' | 'quality' | 'stats' | 'geohash';
+
+/** Bins a geo column into geohash cells for heatmaps. */
+export interface OrcaQueryGeohash {
+  /** Geo column; the first geo column when empty. */
+  field?: string;
+  /** Geohash length, 1-12. Defaults to 6 (cells of about 1.2 km by 0.6 km). */
+  precision?: number;
+  /** Number column aggregated per cell. */
+  value?: string;
+  /** Defaults to avg when value is set. */
+  aggregates?: Array<'sum' | 'avg' | 'min' | 'max'>;
+}
+
+export interface OrcaQueryStats {
+  /** Columns to summarize; all number and text columns when empty. */
+  fields?: string[];
+  /** Histogram bucket count. Defaults to 10. */
+  buckets?: number;
+  /** Fixed histogram bucket width; overrides buckets. */
+  bucketWidth?: number;
+  /** Most frequent text values to return. Defaults to 10. */
+  topK?: number;
+}
+
+export type OrcaDateOrder = 'auto' | 'DMY' | 'MDY' | 'YMD';
+
 export interface OrcaSecureJsonData {
   apiKey?: string;
 }
 
 /** Must extend DataQuery so Grafana supplies refId/hide/etc. */
 export interface OrcaQuery extends DataQuery {
+  /** "quality" returns a per-column data-quality report and "stats" summary statistics instead of rows. */
+  mode?: OrcaQueryMode;
+  stats?: OrcaQueryStats;
+  geohash?: OrcaQueryGeohash;
+  /**
+   * "geojson" returns a FeatureCollection of points located by geoField; "frame" returns
+   * typed data frames built by the backend.
+   */
+  format?: 'rows' | 'geojson' | 'frame';
+  geoField?: string;
   sheetId?: string;
+  sheetName?: string;
   limit?: number;
   skip?: number;
   timeField?: string;
   range?: { from?: string; to?: string };
+  timezone?: string;
+  dashboardTimezone?: string;
+  fieldOptions?: Record<string, OrcaFieldOptions>;
+  /** Forces field types, overriding detection. Keyed by field key or label. */
+  fieldTypes?: Record<string, OrcaFieldType>;
+  /** Overrides the datasource's includeSystemColumns for this query. */
+  systemColumns?: boolean;
+  join?: OrcaQueryJoin;
+  spatial?: OrcaQuerySpatial;
+  sheetIds?: string[];
+  sheetPattern?: string;
+  sourceField?: string;
+  flatten?: OrcaQueryFlatten[];
+  computed?: OrcaComputedColumn[];
+  sort?: Array<{ field: string; desc?: boolean }>;
+  topN?: number;
+  columns?: Array<{ field: string; alias?: string }>;
+}
+
+/** Keeps rows whose geo column lies within every area set. */
+export interface OrcaQuerySpatial {
+  field?: string;
+  /** meters 0 only adds the distance column. */
+  radius?: { lat: number; lon: number; meters: number };
+  /** [minLon, minLat, maxLon, maxLat] */
+  bbox?: [number, number, number, number];
+  /** GeoJSON Polygon or MultiPolygon, bare or wrapped in a Feature or FeatureCollection. */
+  polygon?: object;
+  /** Adds a column with each row's distance in metres from the radius centre. */
+  distanceField?: string;
+}
+
+/** Turns an array or object column into plain values; the result replaces field unless alias is set. */
+export interface OrcaQueryFlatten {
+  field: string;
+  /** join: elements as text; explode: one row per element; path: value at a JSON path; count: number of elements. */
+  strategy: 'join' | 'explode' | 'path' | 'count';
+  /** Separator for join. Defaults to ", ". */
+  separator?: string;
+  /** JSON path for path, e.g. "photos[0].url". */
+  path?: string;
+  alias?: string;
+}
+
+export interface OrcaComputedColumn {
+  name: string;
+  expression: string;
+  decimals?: number;
 }
 
-export type OrcaGrafanaType = 'string' | 'number' | 'boolean' | 'time';
+export interface OrcaFieldOptions {
+  dateOrder?: OrcaDateOrder;
+  /** Extra time layouts such as "DD.MM.YYYY HH:mm", or "epoch", "epoch_ms", "excel". */
+  layouts?: string[];
+  decimalSeparator?: '.' | ',';
+  displayName?: string;
+  unit?: string;
+  decimals?: number;
+  min?: number;
+  max?: number;
+}
+
+export interface OrcaQueryJoin {
+  sheetId?: string;
+  sheetName?: string;
+  leftKey: string;
+  rightKey?: string;
+  type?: 'inner' | 'left';
+  prefix?: string;
+}
+
+export type OrcaFieldType = 'string' | 'number' | 'boolean' | 'time' | 'geo' | 'enum' | 'json' | 'barcode';
+
+export type OrcaGrafanaType = 'string' | 'number' | 'boolean' | 'time' | 'enum' | 'other';
 
 export interface OrcaFieldInfo {
   key: string;
@@ -29,10 +152,30 @@ export interface OrcaFieldInfo {
   grafanaType: OrcaGrafanaType;
   isTime?: boolean;
   decimals?: number;
+  dateOrder?: OrcaDateOrder;
+  dateOrderAmbiguous?: boolean;
+  /** Grafana field config computed by the backend (display name, unit, decimals, min/max). */
+  config?: FieldConfig;
+  /** Share of sampled values that fit the detected type. */
+  confidence?: number;
+  /** Values that did not parse as the field's type and were returned as null. */
+  offenders?: number;
+}
+
+export interface OrcaGeoJSONFeature {
+  type: 'Feature';
+  id?: string | number;
+  geometry: { type: 'Point'; coordinates: number[] } | null;
+  properties: Record<string, any>;
 }
 
 export interface OrcaQueryResponse {
   rows: Array<Record<string, any>>;
+  /** Set for geojson-format queries, which return features instead of rows. */
+  type?: 'FeatureCollection';
+  features?: OrcaGeoJSONFeature[];
+  /** Set for frame-format queries, which return typed frames instead of rows. */
+  frames?: DataFrameJSON[];
   fields: OrcaFieldInfo[];
   refId: string;
   sheetId: string;